@@ -0,0 +1,161 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/potsbo/gocc/target"
+	"github.com/srvc/fail"
+)
+
+const usage = "usage: gocc [-S | -c | --dump-ast[=sexp|json]] [-o output] [--target triple] [-Ldir] [-llib] file... (\"-\" reads C from stdin)"
+
+type mode int
+
+const (
+	// modeLink compiles, assembles and links into an executable
+	modeLink mode = iota
+	// modeAssemble stops after assembling, as with -c
+	modeAssemble
+	// modeCompile stops after generating assembly, as with -S
+	modeCompile
+	// modeDumpAST prints the parsed tree instead of generating any code
+	modeDumpAST
+)
+
+type inputKind int
+
+const (
+	cSource inputKind = iota
+	asmSource
+	linkerInput
+)
+
+type options struct {
+	mode    mode
+	inputs  []string
+	output  string
+	target  target.Target
+	astForm string
+}
+
+// parseArgs parses gcc style command line arguments.
+// Unlike the flag package, options may appear after input files.
+// -l and -L are kept in inputs so that the linker sees them in their original order.
+func parseArgs(args []string) (*options, error) {
+	opts := &options{target: target.Host()}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-S":
+			opts.mode = modeCompile
+		case arg == "-c":
+			opts.mode = modeAssemble
+		case arg == "--dump-ast", strings.HasPrefix(arg, "--dump-ast="):
+			opts.mode = modeDumpAST
+			opts.astForm = "sexp"
+			if form := strings.TrimPrefix(arg, "--dump-ast="); form != arg {
+				if form != "sexp" && form != "json" {
+					return nil, fail.Errorf("unknown AST format %q, expected \"sexp\" or \"json\"", form)
+				}
+				opts.astForm = form
+			}
+		case arg == "-o", arg == "-l", arg == "-L":
+			if i+1 >= len(args) {
+				return nil, fail.Errorf("missing argument after %q\n%s", arg, usage)
+			}
+			i++
+			if arg == "-o" {
+				opts.output = args[i]
+			} else {
+				opts.inputs = append(opts.inputs, arg+args[i])
+			}
+		case arg == "--target", strings.HasPrefix(arg, "--target="):
+			triple := strings.TrimPrefix(arg, "--target=")
+			if arg == "--target" {
+				if i+1 >= len(args) {
+					return nil, fail.Errorf("missing argument after %q\n%s", arg, usage)
+				}
+				i++
+				triple = args[i]
+			}
+			t, err := target.Lookup(triple)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			opts.target = t
+		case strings.HasPrefix(arg, "-o"):
+			opts.output = arg[len("-o"):]
+		case strings.HasPrefix(arg, "-l"), strings.HasPrefix(arg, "-L"):
+			opts.inputs = append(opts.inputs, arg)
+		case arg == "-":
+			opts.inputs = append(opts.inputs, arg)
+		case strings.HasPrefix(arg, "-"):
+			return nil, fail.Errorf("unknown option %q\n%s", arg, usage)
+		default:
+			opts.inputs = append(opts.inputs, arg)
+		}
+	}
+
+	if len(opts.inputs) == 0 {
+		return nil, fail.Errorf("no input files\n%s", usage)
+	}
+	if opts.output != "" && (opts.mode == modeCompile || opts.mode == modeAssemble) && len(opts.translated()) > 1 {
+		return nil, fail.Errorf("cannot specify %q with %q and multiple input files", "-o", opts.mode.flag())
+	}
+	return opts, nil
+}
+
+func (m mode) flag() string {
+	switch m {
+	case modeAssemble:
+		return "-c"
+	case modeCompile:
+		return "-S"
+	case modeDumpAST:
+		return "--dump-ast"
+	}
+	return ""
+}
+
+func kindOf(in string) inputKind {
+	if in == "-" {
+		return cSource
+	}
+	switch filepath.Ext(in) {
+	case ".c":
+		return cSource
+	case ".s", ".S":
+		return asmSource
+	}
+	return linkerInput
+}
+
+// translated returns the inputs which produce an output of their own with -S or -c.
+func (o *options) translated() []string {
+	ins := []string{}
+	for _, in := range o.inputs {
+		if kindOf(in) != linkerInput {
+			ins = append(ins, in)
+		}
+	}
+	return ins
+}
+
+// outputFor returns where the result of translating in should be written with -S or -c.
+// Without -o, foo.c becomes foo.s or foo.o in the current directory.
+// Assembly generated from stdin goes to stdout.
+func (o *options) outputFor(in, ext string) (string, error) {
+	if o.output != "" {
+		return o.output, nil
+	}
+	if in == "-" {
+		if ext == ".s" {
+			return "-", nil
+		}
+		return "", fail.New(`"-o" is required to assemble stdin`)
+	}
+	base := filepath.Base(in)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ext, nil
+}
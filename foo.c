@@ -13,3 +13,14 @@ void alloc(int **p, int a, int b, int c, int d) {
 	pt[3] = d;
 	*p = pt;
 }
+void alloc_chars(char **p, char a, char b, char c, char d) {
+	char *pt = (char *) malloc(4 * sizeof(char));
+	pt[0] = a;
+	pt[1] = b;
+	pt[2] = c;
+	pt[3] = d;
+	*p = pt;
+}
+int weigh8(int a, int b, int c, int d, int e, int f, int g, int h) {
+	return a + b * 2 + c * 3 + d * 4 + e * 5 + f * 6 + g * 7 + h * 8;
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/potsbo/gocc/node"
+	"github.com/potsbo/gocc/target"
 	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
@@ -17,12 +20,14 @@ func main() {
 		debug = true
 	}
 
-	err := compile()
+	err := run(os.Args[1:])
 	if err != nil {
-		aerr := fail.Unwrap(err)
+		aerr := fail.Unwrap(fail.Wrap(err))
 		fmt.Fprintf(os.Stderr, "%s\n", aerr.Error())
-		for _, f := range aerr.StackTrace {
-			fmt.Fprintf(os.Stderr, "%s in %s:L%d\n", f.Func, f.File, f.Line)
+		if debug {
+			for _, f := range aerr.StackTrace {
+				fmt.Fprintf(os.Stderr, "%s in %s:L%d\n", f.Func, f.File, f.Line)
+			}
 		}
 		os.Exit(1)
 	}
@@ -34,39 +39,112 @@ func inspectTokens(tokens []token.Token) {
 	}
 }
 
-func compile() error {
-	if len(os.Args) != 2 {
-		return errors.New("Wrong size of arguments")
+func run(args []string) error {
+	opts, err := parseArgs(args)
+	if err != nil {
+		return fail.Wrap(err)
 	}
+	node.SetTarget(opts.target)
 
-	fmt.Println(".intel_syntax noprefix")
-	fmt.Println(".global _main") // TODO fix
+	return fail.Wrap(build(opts))
+}
 
-	{
-		proc, err := token.Tokenize(os.Args[1])
-		if err != nil {
-			return fail.Wrap(err)
-		}
-		if debug {
-			inspectTokens(proc.Inspect())
-		}
+// compileFile compiles the C source at in, "-" being stdin,
+// and writes the assembly to out, "-" being stdout.
+func compileFile(in, out string, t target.Target) error {
+	src, name, err := readSource(in)
+	if err != nil {
+		return fail.Wrap(err)
+	}
 
-		p := node.NewParser(proc)
+	// buffer the whole output so that a failed compilation leaves no half-written file
+	var buf bytes.Buffer
+	if err := compile(name, src, t, &buf); err != nil {
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(writeOutput(out, &buf))
+}
+
+// dumpFile parses the C source at in and writes its tree to w in form, "sexp" or "json".
+// The tree is dumped as parsed, before type checking, so that a program with type errors can be dumped too.
+func dumpFile(in, form string, w io.Writer) error {
+	src, name, err := readSource(in)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	ns, err := parse(name, src)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if form == "json" {
+		return fail.Wrap(node.DumpJSON(w, ns))
+	}
+	return fail.Wrap(node.DumpSExpr(w, ns))
+}
+
+// writeOutput writes buf to out, "-" or "" being stdout.
+func writeOutput(out string, buf *bytes.Buffer) error {
+	if out == "-" || out == "" {
+		_, err := buf.WriteTo(os.Stdout)
+		return fail.Wrap(err)
+	}
+	return fail.Wrap(ioutil.WriteFile(out, buf.Bytes(), 0644))
+}
 
-		ns, err := p.Parse()
+func readSource(in string) (src, name string, err error) {
+	if in == "-" {
+		b, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			return fail.Wrap(err)
+			return "", "", fail.Wrap(err)
 		}
+		return string(b), "<stdin>", nil
+	}
 
-		for _, n := range ns {
-			lines, err := n.Generate()
-			if err != nil {
-				return fail.Wrap(err)
-			}
-			fmt.Println(lines)
-		}
+	b, err := ioutil.ReadFile(in)
+	if err != nil {
+		return "", "", fail.Wrap(err)
+	}
+	return string(b), in, nil
+}
 
+func parse(name, src string) ([]node.Generatable, error) {
+	proc, err := token.Tokenize(name, src)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if debug {
+		inspectTokens(proc.Inspect())
 	}
 
+	p := node.NewParser(proc)
+	ns, err := p.Parse()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	return ns, nil
+}
+
+func compile(name, src string, t target.Target, w io.Writer) error {
+	ns, err := parse(name, src)
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if err := node.Check(ns); err != nil {
+		return fail.Wrap(err)
+	}
+
+	for _, l := range t.Header() {
+		fmt.Fprintln(w, l)
+	}
+	for _, n := range ns {
+		lines, err := n.Generate()
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		fmt.Fprintln(w, lines)
+	}
+	for _, l := range t.Footer() {
+		fmt.Fprintln(w, l)
+	}
 	return nil
 }
@@ -1,26 +1,48 @@
 package node
 
 import (
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeAddr struct {
-	p Pointable
+type NodeAddr struct {
+	tok *token.Token
+	p   Pointable
 }
 
-func newNodeAddr(p Pointable) TypedNode {
-	return &nodeAddr{p}
+func newNodeAddr(tok *token.Token, p Pointable) TypedNode {
+	return &NodeAddr{tok, p}
 }
 
-func (n *nodeAddr) GeneratePointer() (string, error) {
+func (n *NodeAddr) GeneratePointer() (string, error) {
 	return "", fail.New("Unexpected pointer generation")
 }
 
-func (n *nodeAddr) Generate() (string, error) {
+func (n *NodeAddr) Generate() (string, error) {
 	return n.p.GeneratePointer()
 }
 
-func (n *nodeAddr) Type() types.Type {
+func (n *NodeAddr) Type() types.Type {
 	return types.PointingTo(n.p.Type())
 }
+
+func (n *NodeAddr) Kind() Kind {
+	return Addr
+}
+
+func (n *NodeAddr) Operand() Pointable {
+	return n.p
+}
+
+func (n *NodeAddr) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeAddr) dump() *tree {
+	t := newTree(Addr).withType(n.Type())
+	if g, ok := n.p.(Generatable); ok {
+		t.withNode("operand", g)
+	}
+	return t
+}
@@ -3,27 +3,30 @@ package node
 import (
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeAssign struct {
+type NodeAssign struct {
+	tok *token.Token
 	lhs Pointable
 	rhs Generatable
 }
 
-func newAssign(lhs Pointable, rhs Generatable) TypedNode {
-	return &nodeAssign{
+func newAssign(tok *token.Token, lhs Pointable, rhs Generatable) TypedNode {
+	return &NodeAssign{
+		tok: tok,
 		lhs: lhs,
 		rhs: rhs,
 	}
 }
 
-func (n *nodeAssign) GeneratePointer() (string, error) {
+func (n *NodeAssign) GeneratePointer() (string, error) {
 	return "", NoOffsetError
 }
 
-func (n *nodeAssign) Generate() (string, error) {
+func (n *NodeAssign) Generate() (string, error) {
 	l, err := n.lhs.GeneratePointer()
 	if err != nil {
 		return "", fail.Wrap(err)
@@ -36,17 +39,37 @@ func (n *nodeAssign) Generate() (string, error) {
 	lines := []string{
 		"# assign",
 		l, r,
-		"## pop from stack",
-		"  pop rdi",
-		"  pop rax",
 		"## assign",
-		"  mov [rax], rdi",
-		"  push rdi",
 	}
+	lines = append(lines, store(n.lhs.Type())...)
 
 	return strings.Join(lines, "\n"), nil
 }
 
-func (n *nodeAssign) Type() types.Type {
+func (n *NodeAssign) Type() types.Type {
 	return n.lhs.Type()
 }
+
+func (n *NodeAssign) Kind() Kind {
+	return Assign
+}
+
+func (n *NodeAssign) Lhs() Pointable {
+	return n.lhs
+}
+
+func (n *NodeAssign) Rhs() Generatable {
+	return n.rhs
+}
+
+func (n *NodeAssign) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeAssign) dump() *tree {
+	t := newTree(Assign).withType(n.Type())
+	if g, ok := n.lhs.(Generatable); ok {
+		t.withNode("lhs", g)
+	}
+	return t.withNode("rhs", n.rhs)
+}
@@ -0,0 +1,125 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/potsbo/gocc/types"
+)
+
+// tree is a generic view of a node which can be printed in several formats.
+type tree struct {
+	kind   Kind
+	fields []field
+}
+
+type field struct {
+	key   string
+	value interface{} // string, int, bool, *tree or []*tree
+}
+
+func newTree(k Kind) *tree {
+	return &tree{kind: k}
+}
+
+func (t *tree) with(key string, value interface{}) *tree {
+	t.fields = append(t.fields, field{key, value})
+	return t
+}
+
+func (t *tree) withType(typ types.Type) *tree {
+	if typ == nil {
+		return t
+	}
+	return t.with("type", typ.String())
+}
+
+func (t *tree) withNode(key string, g Generatable) *tree {
+	if g == nil {
+		return t
+	}
+	return t.with(key, g.dump())
+}
+
+func (t *tree) withNodes(key string, gs []Generatable) *tree {
+	ts := make([]*tree, len(gs))
+	for i, g := range gs {
+		ts[i] = g.dump()
+	}
+	return t.with(key, ts)
+}
+
+// MarshalJSON keeps the fields in the order they were added, "node" first.
+func (t *tree) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"node":`)
+	kind, _ := json.Marshal(t.kind.String())
+	buf.Write(kind)
+	for _, f := range t.fields {
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// writeSExpr prints scalar fields on the line of the node and every child on a line of its own.
+func (t *tree) writeSExpr(w *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth+1)
+	w.WriteString("(" + t.kind.String())
+	for _, f := range t.fields {
+		switch v := f.value.(type) {
+		case *tree:
+			w.WriteString("\n" + indent + ":" + f.key + " ")
+			v.writeSExpr(w, depth+1)
+		case []*tree:
+			w.WriteString("\n" + indent + ":" + f.key + " (")
+			for _, c := range v {
+				w.WriteString("\n" + indent + "  ")
+				c.writeSExpr(w, depth+2)
+			}
+			w.WriteString(")")
+		case string:
+			fmt.Fprintf(w, " :%s %q", f.key, v)
+		default:
+			fmt.Fprintf(w, " :%s %v", f.key, v)
+		}
+	}
+	w.WriteString(")")
+}
+
+// DumpJSON writes nodes as an indented JSON array.
+func DumpJSON(w io.Writer, nodes []Generatable) error {
+	ts := make([]*tree, len(nodes))
+	for i, n := range nodes {
+		ts[i] = n.dump()
+	}
+	b, err := json.MarshalIndent(ts, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// DumpSExpr writes nodes as indented S-expressions, one top-level node after another.
+func DumpSExpr(w io.Writer, nodes []Generatable) error {
+	for _, n := range nodes {
+		var b strings.Builder
+		n.dump().writeSExpr(&b, 0)
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,29 +3,33 @@ package node
 import (
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeBinaryOperator struct {
+type NodeBinaryOperator struct {
+	tok  *token.Token
 	kind Kind
 	lhs  Generatable
 	rhs  Generatable
+	t    types.Type
 }
 
-func newBinaryOperator(kind Kind, lhs, rhs Generatable) TypedNode {
-	return &nodeBinaryOperator{
+func newBinaryOperator(tok *token.Token, kind Kind, lhs, rhs Generatable) TypedNode {
+	return &NodeBinaryOperator{
+		tok:  tok,
 		kind: kind,
 		lhs:  lhs,
 		rhs:  rhs,
 	}
 }
 
-func (n *nodeBinaryOperator) GeneratePointer() (string, error) {
+func (n *NodeBinaryOperator) GeneratePointer() (string, error) {
 	return "", NoOffsetError
 }
 
-func (n *nodeBinaryOperator) Generate() (string, error) {
+func (n *NodeBinaryOperator) Generate() (string, error) {
 	l, err := n.lhs.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
@@ -43,7 +47,44 @@ func (n *nodeBinaryOperator) Generate() (string, error) {
 		"  pop rax",
 	}
 
-	switch n.kind {
+	op, err := operate(n.kind)
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	lines = append(lines, op...)
+	lines = append(lines, "  push rax")
+	return strings.Join(lines, "\n"), nil
+}
+
+// Type is nil until the node has been checked.
+func (n *NodeBinaryOperator) Type() types.Type {
+	return n.t
+}
+
+func (n *NodeBinaryOperator) Kind() Kind {
+	return n.kind
+}
+
+func (n *NodeBinaryOperator) Lhs() Generatable {
+	return n.lhs
+}
+
+func (n *NodeBinaryOperator) Rhs() Generatable {
+	return n.rhs
+}
+
+func (n *NodeBinaryOperator) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeBinaryOperator) dump() *tree {
+	return newTree(n.kind).withType(n.Type()).withNode("lhs", n.lhs).withNode("rhs", n.rhs)
+}
+
+// operate applies the binary operator kind to rax and rdi, leaving the result in rax.
+func operate(kind Kind) ([]string, error) {
+	lines := []string{}
+	switch kind {
 	case Add:
 		lines = append(lines, "# Add")
 		lines = append(lines, "  add rax, rdi")
@@ -61,6 +102,28 @@ func (n *nodeBinaryOperator) Generate() (string, error) {
 		lines = append(lines, "  cqo")
 		lines = append(lines, "  idiv rdi")
 		break
+	case Mod:
+		lines = append(lines, "# Mod")
+		lines = append(lines, "  cqo")
+		lines = append(lines, "  idiv rdi")
+		lines = append(lines, "  mov rax, rdx")
+	case BitAnd:
+		lines = append(lines, "# BitAnd")
+		lines = append(lines, "  and rax, rdi")
+	case BitOr:
+		lines = append(lines, "# BitOr")
+		lines = append(lines, "  or rax, rdi")
+	case BitXor:
+		lines = append(lines, "# BitXor")
+		lines = append(lines, "  xor rax, rdi")
+	case Shl:
+		lines = append(lines, "# Shl")
+		lines = append(lines, "  mov rcx, rdi")
+		lines = append(lines, "  sal rax, cl")
+	case Shr:
+		lines = append(lines, "# Shr")
+		lines = append(lines, "  mov rcx, rdi")
+		lines = append(lines, "  sar rax, cl")
 	case NotEqual:
 		lines = append(lines, "# NotEqual")
 		lines = append(lines, "  cmp rax, rdi")
@@ -92,13 +155,8 @@ func (n *nodeBinaryOperator) Generate() (string, error) {
 		lines = append(lines, "  setle al")
 		lines = append(lines, "  movzx rax, al")
 	default:
-		return "", fail.Errorf("Token not supported %d", n.kind)
+		return nil, fail.Errorf("Token not supported %d", kind)
 	}
 
-	lines = append(lines, "  push rax")
-	return strings.Join(lines, "\n"), nil
-}
-
-func (n *nodeBinaryOperator) Type() types.Type {
-	return nil
+	return lines, nil
 }
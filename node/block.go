@@ -3,30 +3,43 @@ package node
 import (
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
 
-type nodeBlock struct {
+type NodeBlock struct {
+	tok   *token.Token
 	stmts []Generatable
 }
 
-func NewNodeBlock(stmts []Generatable) Generatable {
-	return &nodeBlock{stmts}
+func NewNodeBlock(tok *token.Token, stmts []Generatable) Generatable {
+	return &NodeBlock{tok: tok, stmts: stmts}
 }
 
-func (n *nodeBlock) Generate() (string, error) {
+func (n *NodeBlock) Generate() (string, error) {
 	lines := make([]string, len(n.stmts))
 	for i, n := range n.stmts {
 		line, err := n.Generate()
 		if err != nil {
 			return "", fail.Wrap(err)
 		}
-		lines[i] = strings.Join(
-			[]string{
-				line,
-			},
-			"  pop rax\n",
-		)
+		lines[i] = line
 	}
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeBlock) Kind() Kind {
+	return Block
+}
+
+func (n *NodeBlock) Stmts() []Generatable {
+	return n.stmts
+}
+
+func (n *NodeBlock) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeBlock) dump() *tree {
+	return newTree(Block).withNodes("stmts", n.stmts)
+}
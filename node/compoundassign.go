@@ -0,0 +1,108 @@
+package node
+
+import (
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeCompoundAssign is an assignment combined with a binary operator as in a += b,
+// which computes the address of a only once. ++a and a++ are a += 1 too,
+// the postfix one giving the value from before the assignment.
+type NodeCompoundAssign struct {
+	tok     *token.Token
+	op      Kind
+	lhs     Pointable
+	rhs     Generatable
+	postfix bool
+}
+
+func newCompoundAssign(tok *token.Token, op Kind, lhs Pointable, rhs Generatable, postfix bool) TypedNode {
+	return &NodeCompoundAssign{
+		tok:     tok,
+		op:      op,
+		lhs:     lhs,
+		rhs:     rhs,
+		postfix: postfix,
+	}
+}
+
+func (n *NodeCompoundAssign) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeCompoundAssign) Generate() (string, error) {
+	l, err := n.lhs.GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	r, err := n.rhs.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	op, err := operate(n.op)
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+
+	lines := []string{
+		"# compound assign",
+		l,
+		"## keep the address for the store below",
+		"  push [rsp]",
+	}
+	lines = append(lines, load(n.lhs.Type())...)
+	lines = append(lines,
+		r,
+		"  pop rdi",
+		"  pop rax",
+		"  mov rsi, rax",
+	)
+	lines = append(lines, op...)
+	lines = append(lines, "  push rax")
+	lines = append(lines, store(n.lhs.Type())...)
+	if n.postfix {
+		// the old value, which store leaves alone
+		lines = append(lines, "  pop rax", "  push rsi")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeCompoundAssign) Type() types.Type {
+	return n.lhs.Type()
+}
+
+func (n *NodeCompoundAssign) Kind() Kind {
+	return CompoundAssign
+}
+
+// Op returns the binary operator applied before the assignment.
+func (n *NodeCompoundAssign) Op() Kind {
+	return n.op
+}
+
+func (n *NodeCompoundAssign) Lhs() Pointable {
+	return n.lhs
+}
+
+func (n *NodeCompoundAssign) Rhs() Generatable {
+	return n.rhs
+}
+
+func (n *NodeCompoundAssign) Postfix() bool {
+	return n.postfix
+}
+
+func (n *NodeCompoundAssign) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeCompoundAssign) dump() *tree {
+	t := newTree(CompoundAssign).with("op", n.op.String()).with("postfix", n.postfix).withType(n.Type())
+	if g, ok := n.lhs.(Generatable); ok {
+		t.withNode("lhs", g)
+	}
+	return t.withNode("rhs", n.rhs)
+}
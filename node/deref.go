@@ -1,47 +1,69 @@
 package node
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeDeref struct {
+type NodeDeref struct {
+	tok   *token.Token
 	child Generatable
+	t     types.Type
 }
 
-func newNodeDeref(g Generatable) TypedNode {
-	return &nodeDeref{
+func newNodeDeref(tok *token.Token, g Generatable) TypedNode {
+	return &NodeDeref{
+		tok:   tok,
 		child: g,
 	}
 }
 
-func (n *nodeDeref) GeneratePointer() (string, error) {
-	return "", NoOffsetError
+// GeneratePointer pushes the address the child points to, which is simply its value.
+func (n *NodeDeref) GeneratePointer() (string, error) {
+	return n.child.Generate()
 }
 
-func (n *nodeDeref) Generate() (string, error) {
+func (n *NodeDeref) Generate() (string, error) {
 	l, err := n.child.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
 	}
-	return deref(l)
+	return deref(l, n.t)
 }
 
-func (n *nodeDeref) Type() types.Type {
-	return nil // TODO
+// Type is nil until the node has been checked.
+func (n *NodeDeref) Type() types.Type {
+	return n.t
 }
 
-func deref(l string) (string, error) {
+func deref(l string, t types.Type) (string, error) {
+	if t == nil {
+		return "", fail.New("Unexpectedly nil type")
+	}
 	lines := []string{
 		"# LVar",
 		l,
 		"## pushing the var value with following pointer",
-		fmt.Sprintf("  pop rax"),
-		fmt.Sprintf("  mov rax, [rax]"),
-		fmt.Sprintf("  push rax"),
 	}
+	lines = append(lines, load(t)...)
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeDeref) Kind() Kind {
+	return Deref
+}
+
+func (n *NodeDeref) Operand() Generatable {
+	return n.child
+}
+
+func (n *NodeDeref) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeDeref) dump() *tree {
+	return newTree(Deref).withType(n.Type()).withNode("operand", n.child)
+}
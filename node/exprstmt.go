@@ -0,0 +1,43 @@
+package node
+
+import (
+	"github.com/potsbo/gocc/token"
+	"github.com/srvc/fail"
+)
+
+// NodeExprStmt is an expression evaluated for its side effects, whose value is discarded.
+type NodeExprStmt struct {
+	tok  *token.Token
+	expr Generatable
+}
+
+func newExprStmt(tok *token.Token, expr Generatable) Generatable {
+	return &NodeExprStmt{
+		tok:  tok,
+		expr: expr,
+	}
+}
+
+func (n *NodeExprStmt) Generate() (string, error) {
+	l, err := n.expr.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return l + "\n  pop rax", nil
+}
+
+func (n *NodeExprStmt) Kind() Kind {
+	return ExprStmt
+}
+
+func (n *NodeExprStmt) Expr() Generatable {
+	return n.expr
+}
+
+func (n *NodeExprStmt) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeExprStmt) dump() *tree {
+	return newTree(ExprStmt).withNode("expr", n.expr)
+}
@@ -4,39 +4,56 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
 
-type nodeFor struct {
+type NodeFor struct {
+	tok       *token.Token
 	init      Generatable
 	condition Generatable
 	update    Generatable
 	stmt      Generatable
+	labels    *jumpTarget
 }
 
-func newFor(init, c, update, stmt Generatable) Generatable {
-	return &nodeFor{
+func newFor(tok *token.Token, init, c, update, stmt Generatable, labels *jumpTarget) Generatable {
+	return &NodeFor{
+		tok:       tok,
 		init:      init,
 		condition: c,
 		update:    update,
 		stmt:      stmt,
+		labels:    labels,
 	}
 }
 
-func (n *nodeFor) Generate() (string, error) {
+func (n *NodeFor) Generate() (string, error) {
+	// the values of init and update are discarded, so as not to grow the stack every iteration
 	var initLines string
 	var err error
 	if node := n.init; node != nil {
 		if initLines, err = node.Generate(); err != nil {
 			return "", fail.Wrap(err)
 		}
+		initLines += "\n  pop rax"
 	}
 
+	lbegin := fmt.Sprintf(".Lbegin%d", newLabelNum())
+	lend := n.labels.breakLabel
+
+	// without a condition the loop runs until something jumps out of it
 	var conditionLines string
 	if node := n.condition; node != nil {
 		if conditionLines, err = node.Generate(); err != nil {
 			return "", fail.Wrap(err)
 		}
+		conditionLines = strings.Join([]string{
+			conditionLines,
+			"  pop rax",
+			"  cmp rax, 0",
+			"  je " + lend,
+		}, "\n")
 	}
 
 	var stmtLines string
@@ -51,20 +68,16 @@ func (n *nodeFor) Generate() (string, error) {
 		if updateLines, err = node.Generate(); err != nil {
 			return "", fail.Wrap(err)
 		}
+		updateLines += "\n  pop rax"
 	}
 
-	lbegin := fmt.Sprintf(".Lbegin%d", newLabelNum())
-	lend := fmt.Sprintf(".Lend%d", newLabelNum())
-
 	lines := []string{
 		"# forstmt",
 		initLines,
 		lbegin + ":",
 		conditionLines,
-		"  pop rax",
-		"  cmp rax, 0",
-		"  je " + lend,
 		stmtLines,
+		n.labels.continueLabel + ":",
 		updateLines,
 		"  jmp " + lbegin,
 		lend + ":",
@@ -72,3 +85,36 @@ func (n *nodeFor) Generate() (string, error) {
 	}
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeFor) Kind() Kind {
+	return For
+}
+
+// Init, Cond and Update return nil when the clause is omitted.
+func (n *NodeFor) Init() Generatable {
+	return n.init
+}
+
+func (n *NodeFor) Cond() Generatable {
+	return n.condition
+}
+
+func (n *NodeFor) Update() Generatable {
+	return n.update
+}
+
+func (n *NodeFor) Body() Generatable {
+	return n.stmt
+}
+
+func (n *NodeFor) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeFor) dump() *tree {
+	return newTree(For).
+		withNode("init", n.init).
+		withNode("cond", n.condition).
+		withNode("update", n.update).
+		withNode("body", n.stmt)
+}
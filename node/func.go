@@ -4,28 +4,40 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeFunc struct {
+type NodeFunc struct {
+	tok    *token.Token
 	offset int
 	name   string
+	static bool
+	ret    types.Type
 	block  Generatable
 	args   []Pointable
 }
 
-func newNodeFunc(name string, args []Pointable, offset int, block Generatable) Generatable {
-	return &nodeFunc{
+func newNodeFunc(tok *token.Token, name string, static bool, ret types.Type, args []Pointable, offset int, block Generatable) Generatable {
+	return &NodeFunc{
+		tok:    tok,
 		args:   args,
-		offset: offset + len(args)*8,
+		offset: offset,
 		name:   name,
+		static: static,
+		ret:    ret,
 		block:  block,
 	}
 }
 
-func (n *nodeFunc) Generate() (string, error) {
+func (n *NodeFunc) Generate() (string, error) {
 	argsLines := []string{}
 	for i, arg := range n.args {
+		if i >= len(registers) {
+			// passed on the stack, where the parameter is read from as it is
+			break
+		}
 		l, err := arg.GeneratePointer()
 		if err != nil {
 			return "", fail.Wrap(err)
@@ -34,25 +46,83 @@ func (n *nodeFunc) Generate() (string, error) {
 			argsLines,
 			l,
 			"  pop rax",
-			fmt.Sprintf("  mov [rax], %s", registers[i]),
+			storeRegister(arg.Type(), i),
 		)
 	}
 	l, err := n.block.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
 	}
-	lines := []string{
-		fmt.Sprintf("_%s:", n.name),
+	lines := []string{}
+	if !n.static {
+		// static functions stay local to the object file
+		lines = append(lines, codegenTarget.Global(n.name))
+	}
+	lines = append(lines, codegenTarget.FuncBegin(n.name)...)
+	lines = append(lines,
 		"# prologue",
 		"  push rbp",
 		"  mov rbp, rsp",
 		fmt.Sprintf("  sub rsp, %d", n.offset),
-	}
+	)
 
 	lines = append(lines, argsLines...)
 	lines = append(lines,
 		"# prologue end",
 		l,
 	)
+	lines = append(lines, codegenTarget.FuncEnd(n.name)...)
 	return strings.Join(lines, "\n"), nil
 }
+
+// signature has no prototype when there are no parameters, as "(void)" cannot be written.
+func (n *NodeFunc) signature() *signature {
+	sig := &signature{ret: n.ret, unprototyped: len(n.args) == 0}
+	for _, a := range n.args {
+		sig.params = append(sig.params, a.Type())
+	}
+	return sig
+}
+
+func (n *NodeFunc) Kind() Kind {
+	return Func
+}
+
+func (n *NodeFunc) Name() string {
+	return n.name
+}
+
+func (n *NodeFunc) Static() bool {
+	return n.static
+}
+
+func (n *NodeFunc) ReturnType() types.Type {
+	return n.ret
+}
+
+func (n *NodeFunc) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeFunc) Params() []Pointable {
+	return n.args
+}
+
+func (n *NodeFunc) Body() Generatable {
+	return n.block
+}
+
+func (n *NodeFunc) dump() *tree {
+	params := []Generatable{}
+	for _, a := range n.args {
+		if g, ok := a.(Generatable); ok {
+			params = append(params, g)
+		}
+	}
+	return newTree(Func).
+		with("name", n.name).
+		with("static", n.static).
+		with("returns", n.ret.String()).
+		withNodes("params", params).
+		withNode("body", n.block)
+}
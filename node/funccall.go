@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
@@ -19,46 +20,124 @@ var (
 	}
 )
 
-type nodeFuncCall struct {
+// signature is the type of a function as declared by a prototype or a definition.
+type signature struct {
+	ret      types.Type
+	params   []types.Type
+	variadic bool
+	// declared with an empty parameter list, which says nothing about the parameters
+	unprototyped bool
+}
+
+// same reports whether s and o may declare the same function.
+// A declaration without a prototype agrees with any parameters.
+func (s *signature) same(o *signature) bool {
+	if !types.Same(s.ret, o.ret) {
+		return false
+	}
+	if s.unprototyped || o.unprototyped {
+		return true
+	}
+	if len(s.params) != len(o.params) || s.variadic != o.variadic {
+		return false
+	}
+	for i := range s.params {
+		if !types.Same(s.params[i], o.params[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type NodeFuncCall struct {
+	tok  *token.Token
 	name string
 	args []Generatable
-	t    types.Type
+	// nil for a function which has not been declared
+	sig *signature
+	t   types.Type
 }
 
-func newFuncCall(name string, t types.Type, args []Generatable) TypedNode {
-	return &nodeFuncCall{name, args, t}
+// newFuncCall returns a call to the function declared as sig.
+// A function called without a declaration is assumed to return int.
+func newFuncCall(tok *token.Token, name string, sig *signature, args []Generatable) TypedNode {
+	t := types.NewInt()
+	if sig != nil {
+		t = sig.ret
+	}
+	return &NodeFuncCall{tok: tok, name: name, args: args, sig: sig, t: t}
 }
 
-func (n *nodeFuncCall) Generate() (string, error) {
-	lines := []string{}
-	for i, arg := range n.args {
-		if i >= len(registers) {
-			return "", fail.Errorf("No register found for args[%d]", i)
-		}
-		regName := registers[i]
-		l, err := arg.Generate()
+func (n *NodeFuncCall) Generate() (string, error) {
+	stacked := 0
+	if len(n.args) > len(registers) {
+		stacked = len(n.args) - len(registers)
+	}
+	// the ABI requires rsp to be 16-byte aligned at the call,
+	// so rsp is rounded down and the original is saved above the arguments to be restored afterwards
+	lines := []string{
+		"# align rsp",
+		"  mov rax, rsp",
+		"  and rsp, -16",
+	}
+	if stacked%2 == 0 {
+		// the saved rsp and the arguments left on the stack have to add up to a multiple of 16 bytes
+		lines = append(lines, "  sub rsp, 8")
+	}
+	lines = append(lines, "  push rax")
+
+	// evaluated from the last one, so that the arguments which do not fit in the registers
+	// are left on the stack with the 7th on top as the callee expects
+	for i := len(n.args) - 1; i >= 0; i-- {
+		l, err := n.args[i].Generate()
 		if err != nil {
 			return "", fail.Wrap(err)
 		}
-		lines = append(
-			lines,
-			fmt.Sprintf("# args[%d]", i),
-			l,
-			fmt.Sprintf("  pop %s", regName),
-		)
+		lines = append(lines, fmt.Sprintf("# args[%d]", i), l)
+	}
+	for i := 0; i < len(n.args) && i < len(registers); i++ {
+		lines = append(lines, fmt.Sprintf("  pop %s", registers[i]))
+	}
+	if n.sig == nil || n.sig.variadic || n.sig.unprototyped {
+		// al tells a variadic function how many vector registers hold arguments, which are never used
+		lines = append(lines, "  mov al, 0")
+	}
+	lines = append(lines, fmt.Sprintf("  call %s", codegenTarget.CallOperand(n.name)))
+	if stacked > 0 {
+		lines = append(lines, fmt.Sprintf("  add rsp, %d", stacked*8))
 	}
-	lines = append(lines,
-		fmt.Sprintf("  call _%s", n.name),
-		"  push rax",
-	)
+	lines = append(lines, "  pop rsp")
+	// only the low bits of rax are defined for narrow return values
+	lines = append(lines, extendReturnValue(n.t)...)
+	lines = append(lines, "  push rax")
 
 	return strings.Join(lines, "\n"), nil
 }
 
-func (n *nodeFuncCall) GeneratePointer() (string, error) {
+func (n *NodeFuncCall) GeneratePointer() (string, error) {
 	return "", NoOffsetError
 }
 
-func (n *nodeFuncCall) Type() types.Type {
+func (n *NodeFuncCall) Type() types.Type {
 	return n.t
 }
+
+func (n *NodeFuncCall) Kind() Kind {
+	return FuncCall
+}
+
+func (n *NodeFuncCall) Name() string {
+	return n.name
+}
+
+func (n *NodeFuncCall) Args() []Generatable {
+	return n.args
+}
+
+func (n *NodeFuncCall) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeFuncCall) dump() *tree {
+	return newTree(FuncCall).with("name", n.name).withType(n.t).withNodes("args", n.args)
+}
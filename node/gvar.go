@@ -0,0 +1,164 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeGVar is a reference to a global variable, addressed relative to rip.
+type NodeGVar struct {
+	tok  *token.Token
+	name string
+	t    types.Type
+}
+
+func newGVar(tok *token.Token, name string, t types.Type) TypedNode {
+	return &NodeGVar{
+		tok:  tok,
+		name: name,
+		t:    t,
+	}
+}
+
+func (n *NodeGVar) GeneratePointer() (string, error) {
+	lines := []string{
+		fmt.Sprintf("## push global var pointer %q", n.name),
+		fmt.Sprintf("  lea rax, [rip + %s]", codegenTarget.Symbol(n.name)),
+		"  push rax",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeGVar) Generate() (string, error) {
+	l, err := n.GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return deref(l, n.t)
+}
+
+func (n *NodeGVar) Type() types.Type {
+	return n.t
+}
+
+func (n *NodeGVar) Kind() Kind {
+	return GVar
+}
+
+func (n *NodeGVar) Name() string {
+	return n.name
+}
+
+func (n *NodeGVar) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeGVar) dump() *tree {
+	return newTree(GVar).with("name", n.name).withType(n.t)
+}
+
+// NodeGVarDef is the definition of a global variable.
+type NodeGVarDef struct {
+	tok    *token.Token
+	name   string
+	static bool
+	t      types.Type
+	init   []Generatable
+	values []int
+}
+
+func newGVarDef(tok *token.Token, name string, static bool, t types.Type, init []Generatable) Generatable {
+	return &NodeGVarDef{
+		tok:    tok,
+		name:   name,
+		static: static,
+		t:      t,
+		init:   init,
+	}
+}
+
+// Generate emits the variable into .data, or into .bss when it has no initializer.
+func (n *NodeGVarDef) Generate() (string, error) {
+	lines := []string{}
+	if !n.static {
+		lines = append(lines, codegenTarget.Global(n.name))
+	}
+	if n.init == nil {
+		lines = append(lines, codegenTarget.ZeroFilled(n.name, n.t.Size(), n.t.Align())...)
+		return strings.Join(lines, "\n"), nil
+	}
+	if len(n.values) != len(n.init) {
+		return "", fail.New("Unexpectedly unchecked initializer")
+	}
+
+	lines = append(lines, ".data")
+	lines = append(lines, codegenTarget.Object(n.name, n.t.Size(), n.t.Align())...)
+	directive := dataDirective(scalarType(n.t))
+	for _, v := range n.values {
+		lines = append(lines, fmt.Sprintf("  %s %d", directive, v))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// scalarType returns the type of the scalars an object of type t consists of.
+func scalarType(t types.Type) types.Type {
+	for t.Kind() == types.Array {
+		t = t.PointingTo()
+	}
+	return t
+}
+
+func dataDirective(t types.Type) string {
+	switch t.Size() {
+	case 1:
+		return ".byte"
+	case 4:
+		return ".long"
+	}
+	return ".quad"
+}
+
+func (n *NodeGVarDef) Kind() Kind {
+	return GVarDef
+}
+
+func (n *NodeGVarDef) Name() string {
+	return n.name
+}
+
+func (n *NodeGVarDef) Static() bool {
+	return n.static
+}
+
+func (n *NodeGVarDef) Type() types.Type {
+	return n.t
+}
+
+// Init returns one initializer per scalar of the variable, nil being zero.
+// It returns nil for a variable without an initializer.
+func (n *NodeGVarDef) Init() []Generatable {
+	return n.init
+}
+
+func (n *NodeGVarDef) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeGVarDef) dump() *tree {
+	t := newTree(GVarDef).with("name", n.name).with("static", n.static).withType(n.t)
+	if n.init == nil {
+		return t
+	}
+	init := make([]Generatable, len(n.init))
+	for i, g := range n.init {
+		if g == nil {
+			g = newnodeImplNum(n.tok, 0)
+		}
+		init[i] = g
+	}
+	return t.withNodes("init", init)
+}
@@ -4,24 +4,27 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
 
-type nodeIf struct {
+type NodeIf struct {
+	tok            *token.Token
 	condition      Generatable
 	trueStatement  Generatable
 	falseStatement Generatable
 }
 
-func newIf(c, t, f Generatable) Generatable {
-	return &nodeIf{
+func newIf(tok *token.Token, c, t, f Generatable) Generatable {
+	return &NodeIf{
+		tok:            tok,
 		condition:      c,
 		trueStatement:  t,
 		falseStatement: f,
 	}
 }
 
-func (n *nodeIf) Generate() (string, error) {
+func (n *NodeIf) Generate() (string, error) {
 	condition, err := n.condition.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
@@ -45,7 +48,7 @@ func (n *nodeIf) Generate() (string, error) {
 		"  cmp rax, 0",
 		"  je  " + lelse,
 		ts,
-		"  je  " + lend,
+		"  jmp " + lend,
 		lelse + ":",
 		fs,
 		lend + ":",
@@ -53,3 +56,31 @@ func (n *nodeIf) Generate() (string, error) {
 	}
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeIf) Kind() Kind {
+	return If
+}
+
+func (n *NodeIf) Cond() Generatable {
+	return n.condition
+}
+
+func (n *NodeIf) Then() Generatable {
+	return n.trueStatement
+}
+
+// Else returns a NodeNop when there is no else clause.
+func (n *NodeIf) Else() Generatable {
+	return n.falseStatement
+}
+
+func (n *NodeIf) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeIf) dump() *tree {
+	return newTree(If).
+		withNode("cond", n.condition).
+		withNode("then", n.trueStatement).
+		withNode("else", n.falseStatement)
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/potsbo/gocc/token"
+)
+
+// jumpTarget holds the labels a break or continue inside a loop jumps to.
+// A switch has no continue label, so a continue in it belongs to the enclosing loop.
+type jumpTarget struct {
+	breakLabel    string
+	continueLabel string
+}
+
+// enterLoop makes the labels of a loop whose body is about to be parsed.
+// The continue label is named after where it sits: the condition of a while, the update of a for.
+func (p *Parser) enterLoop(continuePrefix string) *jumpTarget {
+	t := &jumpTarget{
+		breakLabel:    fmt.Sprintf(".Lend%d", newLabelNum()),
+		continueLabel: fmt.Sprintf("%s%d", continuePrefix, newLabelNum()),
+	}
+	p.jumpTargets = append(p.jumpTargets, t)
+	return t
+}
+
+func (p *Parser) enterSwitch() *jumpTarget {
+	t := &jumpTarget{breakLabel: fmt.Sprintf(".Lend%d", newLabelNum())}
+	p.jumpTargets = append(p.jumpTargets, t)
+	return t
+}
+
+func (p *Parser) leaveJumpTarget() {
+	p.jumpTargets = p.jumpTargets[:len(p.jumpTargets)-1]
+}
+
+// jump resolves a break or continue against the innermost enclosing statement which accepts it.
+func (p *Parser) jump(tok *token.Token, k Kind) (Generatable, error) {
+	for i := len(p.jumpTargets) - 1; i >= 0; i-- {
+		label := p.jumpTargets[i].breakLabel
+		if k == Continue {
+			label = p.jumpTargets[i].continueLabel
+		}
+		if label != "" {
+			return &NodeJump{tok: tok, kind: k, label: label}, nil
+		}
+	}
+	if k == Break {
+		return nil, p.tokenProcessor.ErrorAt(tok, "%q statement not in loop or switch statement", tok.Str)
+	}
+	return nil, p.tokenProcessor.ErrorAt(tok, "%q statement not in loop", tok.Str)
+}
+
+// NodeJump is a break or continue statement.
+type NodeJump struct {
+	tok   *token.Token
+	kind  Kind
+	label string
+}
+
+func (n *NodeJump) Generate() (string, error) {
+	return "  jmp " + n.label, nil
+}
+
+func (n *NodeJump) Kind() Kind {
+	return n.kind
+}
+
+func (n *NodeJump) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeJump) dump() *tree {
+	return newTree(n.kind)
+}
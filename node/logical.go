@@ -0,0 +1,91 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeLogical is && or ||, which evaluates the rhs only when the lhs does not decide the result.
+type NodeLogical struct {
+	tok  *token.Token
+	kind Kind
+	lhs  Generatable
+	rhs  Generatable
+}
+
+func newLogical(tok *token.Token, kind Kind, lhs, rhs Generatable) TypedNode {
+	return &NodeLogical{
+		tok:  tok,
+		kind: kind,
+		lhs:  lhs,
+		rhs:  rhs,
+	}
+}
+
+func (n *NodeLogical) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeLogical) Generate() (string, error) {
+	l, err := n.lhs.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	r, err := n.rhs.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+
+	// && gives up as soon as a side is 0, and || as soon as a side is not
+	jump, decided, otherwise := "je", 0, 1
+	if n.kind == LogicalOr {
+		jump, decided, otherwise = "jne", 1, 0
+	}
+	ldecided := fmt.Sprintf(".Ldecided%d", newLabelNum())
+	lend := fmt.Sprintf(".Lend%d", newLabelNum())
+	lines := []string{
+		fmt.Sprintf("# %s", n.kind),
+		l,
+		"  pop rax",
+		"  cmp rax, 0",
+		fmt.Sprintf("  %s %s", jump, ldecided),
+		r,
+		"  pop rax",
+		"  cmp rax, 0",
+		fmt.Sprintf("  %s %s", jump, ldecided),
+		fmt.Sprintf("  push %d", otherwise),
+		"  jmp " + lend,
+		ldecided + ":",
+		fmt.Sprintf("  push %d", decided),
+		lend + ":",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeLogical) Type() types.Type {
+	return types.NewInt()
+}
+
+func (n *NodeLogical) Kind() Kind {
+	return n.kind
+}
+
+func (n *NodeLogical) Lhs() Generatable {
+	return n.lhs
+}
+
+func (n *NodeLogical) Rhs() Generatable {
+	return n.rhs
+}
+
+func (n *NodeLogical) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeLogical) dump() *tree {
+	return newTree(n.kind).withType(n.Type()).withNode("lhs", n.lhs).withNode("rhs", n.rhs)
+}
@@ -4,32 +4,39 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
 )
 
-type nodeLValue struct {
+type NodeLValue struct {
+	tok    *token.Token
 	name   string
 	offset int
 	t      types.Type
 }
 
-func newLValue(name string, offset int, t types.Type) TypedNode {
-	return &nodeLValue{
+func newLValue(tok *token.Token, name string, offset int, t types.Type) TypedNode {
+	return &NodeLValue{
+		tok:    tok,
 		name:   name,
 		offset: offset,
 		t:      t,
 	}
 }
 
-func (n *nodeLValue) GeneratePointer() (string, error) {
+func (n *NodeLValue) GeneratePointer() (string, error) {
 	if n.t == nil {
 		return "", fail.New("Unexpectedly nil type")
 	}
+	move := fmt.Sprintf("  sub rax, %d", n.offset)
+	if n.offset < 0 {
+		move = fmt.Sprintf("  add rax, %d", -n.offset)
+	}
 	lines := []string{
 		fmt.Sprintf("## push var pointer %q", n.name),
 		fmt.Sprintf("  mov rax, rbp"),
-		fmt.Sprintf("  sub rax, %d", n.offset),
+		move,
 		fmt.Sprintf("  push rax"),
 		"## end",
 	}
@@ -37,11 +44,36 @@ func (n *nodeLValue) GeneratePointer() (string, error) {
 	return strings.Join(lines, "\n"), nil
 }
 
-func (n *nodeLValue) Generate() (string, error) {
-	addr := newNodeAddr(n)
-	return newNodeDeref(addr).Generate()
+func (n *NodeLValue) Generate() (string, error) {
+	l, err := n.GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return deref(l, n.t)
 }
 
-func (n *nodeLValue) Type() types.Type {
+func (n *NodeLValue) Type() types.Type {
 	return n.t
 }
+
+func (n *NodeLValue) Kind() Kind {
+	return LVar
+}
+
+func (n *NodeLValue) Name() string {
+	return n.name
+}
+
+// Offset is the distance of the variable below rbp.
+// It is negative for a parameter passed on the stack, which lies above rbp.
+func (n *NodeLValue) Offset() int {
+	return n.offset
+}
+
+func (n *NodeLValue) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeLValue) dump() *tree {
+	return newTree(LVar).with("name", n.name).with("offset", n.offset).withType(n.t)
+}
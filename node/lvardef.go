@@ -0,0 +1,48 @@
+package node
+
+import (
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+)
+
+// NodeLVarDef is the declaration of a local variable.
+// It generates nothing, the variable being allocated with the stack frame of the function.
+type NodeLVarDef struct {
+	tok    *token.Token
+	name   string
+	offset int
+	t      types.Type
+}
+
+func newLVarDef(tok *token.Token, v *NodeLValue) Generatable {
+	return &NodeLVarDef{
+		tok:    tok,
+		name:   v.name,
+		offset: v.offset,
+		t:      v.t,
+	}
+}
+
+func (n *NodeLVarDef) Generate() (string, error) {
+	return "", nil
+}
+
+func (n *NodeLVarDef) Kind() Kind {
+	return LVarDef
+}
+
+func (n *NodeLVarDef) Name() string {
+	return n.name
+}
+
+func (n *NodeLVarDef) Type() types.Type {
+	return n.t
+}
+
+func (n *NodeLVarDef) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeLVarDef) dump() *tree {
+	return newTree(LVarDef).with("name", n.name).with("offset", n.offset).withType(n.t)
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeMember is a member access s.m of a struct or union. p->m is parsed as (*p).m.
+type NodeMember struct {
+	tok    *token.Token
+	of     Generatable
+	name   string
+	member *types.Member
+}
+
+func newMember(tok *token.Token, of Generatable, name string) TypedNode {
+	return &NodeMember{
+		tok:  tok,
+		of:   of,
+		name: name,
+	}
+}
+
+// GeneratePointer pushes the address of the struct moved forward by the offset of the member.
+func (n *NodeMember) GeneratePointer() (string, error) {
+	if n.member == nil {
+		return "", fail.New("Unexpectedly unchecked member access")
+	}
+	l, err := n.of.(Pointable).GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	lines := []string{
+		l,
+		fmt.Sprintf("## member %q", n.name),
+		"  pop rax",
+		fmt.Sprintf("  add rax, %d", n.member.Offset),
+		"  push rax",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeMember) Generate() (string, error) {
+	l, err := n.GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return deref(l, n.Type())
+}
+
+// Type is nil until the node has been checked.
+func (n *NodeMember) Type() types.Type {
+	if n.member == nil {
+		return nil
+	}
+	return n.member.Type
+}
+
+func (n *NodeMember) Kind() Kind {
+	return Member
+}
+
+// Of returns the struct whose member is accessed.
+func (n *NodeMember) Of() Generatable {
+	return n.of
+}
+
+func (n *NodeMember) Name() string {
+	return n.name
+}
+
+// Offset is the distance of the member from the head of the struct, 0 until checked.
+func (n *NodeMember) Offset() int {
+	if n.member == nil {
+		return 0
+	}
+	return n.member.Offset
+}
+
+func (n *NodeMember) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeMember) dump() *tree {
+	return newTree(Member).with("name", n.name).with("offset", n.Offset()).withType(n.Type()).withNode("of", n.of)
+}
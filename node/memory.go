@@ -0,0 +1,84 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/potsbo/gocc/types"
+)
+
+var (
+	// argument registers narrowed to 4 and 1 bytes, in the order of registers
+	registers32 = []string{"edi", "esi", "edx", "ecx", "r8d", "r9d"}
+	registers8  = []string{"dil", "sil", "dl", "cl", "r8b", "r9b"}
+)
+
+// load replaces the address on top of the stack with the value of type t stored there,
+// sign-extended to 64 bits.
+// An array is not loaded at all, as its value is the address of its first element.
+// Neither is a struct or union, which is handled by its address.
+func load(t types.Type) []string {
+	if t.Kind() == types.Array || t.Kind().HasMembers() {
+		return nil
+	}
+	lines := []string{"  pop rax"}
+	switch t.Size() {
+	case 1:
+		lines = append(lines, "  movsx rax, byte ptr [rax]")
+	case 4:
+		lines = append(lines, "  movsxd rax, dword ptr [rax]")
+	default:
+		lines = append(lines, "  mov rax, [rax]")
+	}
+	return append(lines, "  push rax")
+}
+
+// store pops a value and then an address, writes the value there with the width of t,
+// and pushes the value back as the result of the assignment,
+// sign-extended from the width of t as if it had been loaded again.
+// A struct or union is copied byte by byte from the address which is its value.
+func store(t types.Type) []string {
+	if t.Kind().HasMembers() {
+		lines := []string{"  pop rdi", "  pop rax"}
+		for i := 0; i < t.Size(); i++ {
+			lines = append(lines,
+				fmt.Sprintf("  mov r8b, [rdi+%d]", i),
+				fmt.Sprintf("  mov [rax+%d], r8b", i),
+			)
+		}
+		return append(lines, "  push rdi")
+	}
+	lines := []string{
+		"  pop rdi",
+		"  pop rax",
+		storeRegister(t, 0),
+	}
+	switch t.Size() {
+	case 1:
+		lines = append(lines, "  movsx rdi, dil")
+	case 4:
+		lines = append(lines, "  movsxd rdi, edi")
+	}
+	return append(lines, "  push rdi")
+}
+
+// storeRegister writes the i-th argument register to the address in rax with the width of t.
+func storeRegister(t types.Type, i int) string {
+	switch t.Size() {
+	case 1:
+		return fmt.Sprintf("  mov byte ptr [rax], %s", registers8[i])
+	case 4:
+		return fmt.Sprintf("  mov dword ptr [rax], %s", registers32[i])
+	}
+	return fmt.Sprintf("  mov [rax], %s", registers[i])
+}
+
+// extendReturnValue sign-extends a returned value of type t to the whole of rax.
+func extendReturnValue(t types.Type) []string {
+	switch t.Size() {
+	case 1:
+		return []string{"  movsx rax, al"}
+	case 4:
+		return []string{"  movsxd rax, eax"}
+	}
+	return nil
+}
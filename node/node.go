@@ -3,6 +3,7 @@ package node
 import (
 	"errors"
 
+	"github.com/potsbo/gocc/target"
 	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 )
@@ -32,8 +33,138 @@ const (
 	Block
 	FuncCall
 	Nop
+	Deref
+	Addr
+	Sizeof
+	GVar
+	GVarDef
+	Str
+	Member
+	LogicalAnd
+	LogicalOr
+	Not
+	Mod
+	BitAnd
+	BitOr
+	BitXor
+	BitNot
+	Shl
+	Shr
+	CompoundAssign
+	Ternary
+	Comma
+	Break
+	Continue
+	Switch
+	Case
+	Default
+	ExprStmt
+	LVarDef
 )
 
+func (k Kind) String() string {
+	switch k {
+	case Add:
+		return "Add"
+	case Sub:
+		return "Sub"
+	case Mul:
+		return "Mul"
+	case Div:
+		return "Div"
+	case Num:
+		return "Num"
+	case Equal:
+		return "Equal"
+	case NotEqual:
+		return "NotEqual"
+	case SmallerThanOrEqualTo:
+		return "SmallerThanOrEqualTo"
+	case GreaterThanOrEqualTo:
+		return "GreaterThanOrEqualTo"
+	case SmallerThan:
+		return "SmallerThan"
+	case GreaterThan:
+		return "GreaterThan"
+	case LVar:
+		return "LVar"
+	case Assign:
+		return "Assign"
+	case Return:
+		return "Return"
+	case Func:
+		return "Func"
+	case If:
+		return "If"
+	case While:
+		return "While"
+	case For:
+		return "For"
+	case Block:
+		return "Block"
+	case FuncCall:
+		return "FuncCall"
+	case Nop:
+		return "Nop"
+	case Deref:
+		return "Deref"
+	case Addr:
+		return "Addr"
+	case Sizeof:
+		return "Sizeof"
+	case GVar:
+		return "GVar"
+	case GVarDef:
+		return "GVarDef"
+	case Str:
+		return "Str"
+	case Member:
+		return "Member"
+	case LogicalAnd:
+		return "LogicalAnd"
+	case LogicalOr:
+		return "LogicalOr"
+	case Not:
+		return "Not"
+	case Mod:
+		return "Mod"
+	case BitAnd:
+		return "BitAnd"
+	case BitOr:
+		return "BitOr"
+	case BitXor:
+		return "BitXor"
+	case BitNot:
+		return "BitNot"
+	case Shl:
+		return "Shl"
+	case Shr:
+		return "Shr"
+	case CompoundAssign:
+		return "CompoundAssign"
+	case Ternary:
+		return "Ternary"
+	case Comma:
+		return "Comma"
+	case Break:
+		return "Break"
+	case Continue:
+		return "Continue"
+	case Switch:
+		return "Switch"
+	case Case:
+		return "Case"
+	case Default:
+		return "Default"
+	case ExprStmt:
+		return "ExprStmt"
+	case LVarDef:
+		return "LVarDef"
+	default:
+		return "Unknown"
+	}
+}
+
 func (k Kind) Token() *token.Token {
 	switch k {
 	case Equal:
@@ -47,8 +178,14 @@ func (k Kind) Token() *token.Token {
 var (
 	NoOffsetError = errors.New("Node is not LVal")
 	labelNum      = 0
+	codegenTarget = target.Host()
 )
 
+// SetTarget switches the platform conventions used by Generate.
+func SetTarget(t target.Target) {
+	codegenTarget = t
+}
+
 type Node interface {
 	Generatable
 	Pointable
@@ -58,26 +195,9 @@ type TypedNode interface {
 	Node
 }
 
-func wrap(n Node, t types.Type) TypedNode {
-	if n == nil {
-		return nil
-	}
-	return &typedNodeImpl{
-		n, t,
-	}
-}
-
-func (t typedNodeImpl) Type() types.Type {
-	return t.t
-}
-
-type typedNodeImpl struct {
-	Node
-	t types.Type
-}
-
 type Generatable interface {
 	Generate() (string, error)
+	dump() *tree
 }
 
 type Pointable interface {
@@ -94,7 +214,9 @@ func newLabelNum() int {
 	return labelNum
 }
 
-type nopNode struct{}
+type NodeNop struct{}
 
-func (n nopNode) Generate() (string, error)        { return "", nil }
-func (n nopNode) GeneratePointer() (string, error) { return "", nil }
+func (n NodeNop) Generate() (string, error)        { return "", nil }
+func (n NodeNop) GeneratePointer() (string, error) { return "", nil }
+func (n NodeNop) Kind() Kind                       { return Nop }
+func (n NodeNop) dump() *tree                      { return newTree(Nop) }
@@ -3,27 +3,46 @@ package node
 import (
 	"fmt"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 )
 
-type nodeNum struct {
+type NodeNum struct {
+	tok *token.Token
 	val int
 }
 
-func newnodeImplNum(n int) TypedNode {
-	return &nodeNum{
+func newnodeImplNum(tok *token.Token, n int) TypedNode {
+	return &NodeNum{
+		tok: tok,
 		val: n,
 	}
 }
 
-func (n *nodeNum) Generate() (string, error) {
+func (n *NodeNum) Generate() (string, error) {
 	return fmt.Sprintf("# Num\n  push %d", n.val), nil
 }
 
-func (n *nodeNum) GeneratePointer() (string, error) {
+func (n *NodeNum) GeneratePointer() (string, error) {
 	return "", NoOffsetError
 }
 
-func (n *nodeNum) Type() types.Type {
+func (n *NodeNum) Type() types.Type {
 	return types.NewInt()
 }
+
+func (n *NodeNum) Kind() Kind {
+	return Num
+}
+
+func (n *NodeNum) Value() int {
+	return n.val
+}
+
+func (n *NodeNum) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeNum) dump() *tree {
+	return newTree(Num).with("value", n.val).withType(n.Type())
+}
@@ -1,8 +1,6 @@
 package node
 
 import (
-	"errors"
-
 	"github.com/potsbo/gocc/token"
 	"github.com/potsbo/gocc/types"
 	"github.com/srvc/fail"
@@ -10,23 +8,22 @@ import (
 
 type Parser struct {
 	tokenProcessor *token.Processor
-	locals         map[string]lvar
-}
-
-type lvar struct {
-	Type   types.Type
-	name   string
-	offset int
-	size   int
+	scopes         []*scope
+	stackSize      int
+	// the innermost statement last, for break and continue to jump out of
+	jumpTargets []*jumpTarget
+	// the innermost switch last, for case and default labels to register with
+	switches []*NodeSwitch
 }
 
 type declaration struct {
 	name string
 	Type types.Type
+	tok  *token.Token
 }
 
 func NewParser(t *token.Processor) Parser {
-	return Parser{tokenProcessor: t}
+	return Parser{tokenProcessor: t, scopes: []*scope{newScope()}}
 }
 
 type parseFunc func() (Node, error)
@@ -49,206 +46,411 @@ func (p *Parser) tryBinaryOperator(k Kind, lhsGetter, rhsGetter parseFunc) parse
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
-		return newBinaryOperator(k, lhs, rhs), nil
+		return newBinaryOperator(t, k, lhs, rhs), nil
 	}
 }
 
-func (p *Parser) equality() (TypedNode, error) {
-	node, err := p.relational()
+func (p *Parser) logOr() (TypedNode, error) {
+	node, err := p.logAnd()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
 
 	for {
-		if p.tokenProcessor.ConsumeReserved("==") {
-			r, err := p.relational()
-			if err != nil {
-				return nil, fail.Wrap(err)
-			}
-			node = newBinaryOperator(Equal, node, r)
-			continue
+		tok := p.tokenProcessor.Peek()
+		if !p.tokenProcessor.ConsumeReserved("||") {
+			return node, nil
 		}
-
-		if p.tokenProcessor.ConsumeReserved("!=") {
-			r, err := p.relational()
-			if err != nil {
-				return nil, fail.Wrap(err)
-			}
-			node = newBinaryOperator(NotEqual, node, r)
-			continue
+		r, err := p.logAnd()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if node == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", tok.Str)
 		}
-		return wrap(node, types.NewInt()), nil
+		node = newLogical(tok, LogicalOr, node, r)
 	}
 }
 
-func (p *Parser) relational() (Node, error) {
-	node, err := p.add()
+func (p *Parser) logAnd() (TypedNode, error) {
+	node, err := p.bitOr()
 	if err != nil {
-		return nil, err
+		return nil, fail.Wrap(err)
 	}
 
 	for {
-		if p.tokenProcessor.ConsumeReserved("<=") {
-			r, err := p.add()
-			if err != nil {
-				return nil, err
-			}
-			node = newBinaryOperator(SmallerThanOrEqualTo, node, r)
-			continue
-		}
-		if p.tokenProcessor.ConsumeReserved(">=") {
-			r, err := p.add()
-			if err != nil {
-				return nil, err
-			}
-			node = newBinaryOperator(GreaterThanOrEqualTo, node, r)
-			continue
+		tok := p.tokenProcessor.Peek()
+		if !p.tokenProcessor.ConsumeReserved("&&") {
+			return node, nil
 		}
-		if p.tokenProcessor.ConsumeReserved("<") {
-			r, err := p.add()
-			if err != nil {
-				return nil, err
-			}
-			node = newBinaryOperator(SmallerThan, node, r)
-			continue
+		r, err := p.bitOr()
+		if err != nil {
+			return nil, fail.Wrap(err)
 		}
-		if p.tokenProcessor.ConsumeReserved(">") {
-			r, err := p.add()
-			if err != nil {
-				return nil, err
-			}
-			node = newBinaryOperator(GreaterThan, node, r)
-			continue
+		if node == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", tok.Str)
 		}
-		return node, nil
+		node = newLogical(tok, LogicalAnd, node, r)
 	}
 }
 
-func (p *Parser) add() (Node, error) {
-	node, err := p.mul()
-	if err != nil {
-		return nil, err
-	}
+func (p *Parser) bitOr() (TypedNode, error) {
+	return p.leftAssoc(p.bitXor, map[string]Kind{"|": BitOr})
+}
 
-	for {
-		if p.tokenProcessor.ConsumeReserved("+") {
-			r, err := p.mul()
-			if err != nil {
-				return nil, err
-			}
-			if node.Type() != nil && node.Type().Kind() == types.Pointer { // TODO: every TypedNode should return non nil Type
-				r = newBinaryOperator(Mul, r, newnodeImplNum(node.Type().PointingTo().Kind().Size()))
-			}
-			node = newBinaryOperator(Add, node, r)
-			continue
-		}
-		if p.tokenProcessor.ConsumeReserved("-") {
-			r, err := p.mul()
-			if err != nil {
-				return nil, err
-			}
-			node = newBinaryOperator(Sub, node, r)
-			continue
-		}
-		return node, nil
-	}
+func (p *Parser) bitXor() (TypedNode, error) {
+	return p.leftAssoc(p.bitAnd, map[string]Kind{"^": BitXor})
 }
 
-func (p *Parser) mul() (TypedNode, error) {
-	node, err := p.unary()
+func (p *Parser) bitAnd() (TypedNode, error) {
+	return p.leftAssoc(p.equality, map[string]Kind{"&": BitAnd})
+}
+
+// leftAssoc parses operands joined by the operators in ops, which are all of the same precedence.
+func (p *Parser) leftAssoc(operand func() (TypedNode, error), ops map[string]Kind) (TypedNode, error) {
+	node, err := operand()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
 
 	for {
-		if p.tokenProcessor.ConsumeReserved("*") {
-			r, err := p.unary()
-			if err != nil {
-				return nil, fail.Wrap(err)
-			}
-			node = newBinaryOperator(Mul, node, r)
-			continue
+		tok := p.tokenProcessor.Peek()
+		kind, ok := ops[tok.Str]
+		if !ok || !p.tokenProcessor.ConsumeReserved(tok.Str) {
+			return node, nil
 		}
-
-		if p.tokenProcessor.ConsumeReserved("/") {
-			r, err := p.unary()
-			if err != nil {
-				return nil, fail.Wrap(err)
-			}
-			node = newBinaryOperator(Div, node, r)
+		r, err := operand()
+		if err != nil {
+			return nil, fail.Wrap(err)
 		}
-		return node, nil
+		if node == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", tok.Str)
+		}
+		node = newBinaryOperator(tok, kind, node, r)
 	}
 }
 
+func (p *Parser) equality() (TypedNode, error) {
+	return p.leftAssoc(p.relational, map[string]Kind{"==": Equal, "!=": NotEqual})
+}
+
+func (p *Parser) relational() (TypedNode, error) {
+	return p.leftAssoc(p.shift, map[string]Kind{
+		"<=": SmallerThanOrEqualTo,
+		">=": GreaterThanOrEqualTo,
+		"<":  SmallerThan,
+		">":  GreaterThan,
+	})
+}
+
+func (p *Parser) shift() (TypedNode, error) {
+	return p.leftAssoc(p.add, map[string]Kind{"<<": Shl, ">>": Shr})
+}
+
+func (p *Parser) add() (TypedNode, error) {
+	return p.leftAssoc(p.mul, map[string]Kind{"+": Add, "-": Sub})
+}
+
+func (p *Parser) mul() (TypedNode, error) {
+	return p.leftAssoc(p.unary, map[string]Kind{"*": Mul, "/": Div, "%": Mod})
+}
+
 func (p *Parser) program() ([]Generatable, error) {
-	funcs := []Generatable{}
+	decls := []Generatable{}
 
 	for {
 		if p.tokenProcessor.Finished() {
 			break
 		}
-		n, err := p.funcDef()
+		n, err := p.topLevel()
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
 		if n == nil {
-			return nil, errors.New("No function found")
+			return nil, p.tokenProcessor.Errorf("expected a function or variable definition")
+		}
+		if _, ok := n.(NodeNop); ok {
+			continue
 		}
-		funcs = append(funcs, n)
+		decls = append(decls, n)
 	}
 
-	return funcs, nil
+	return decls, nil
 }
 
-func (p *Parser) funcDef() (Generatable, error) {
-	p.resetLocal()
+// topLevel parses a function or global variable definition.
+func (p *Parser) topLevel() (Generatable, error) {
+	if p.tokenProcessor.ConsumeKind(token.Typedef) != nil {
+		if err := p.typedef(); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		return NodeNop{}, p.tokenProcessor.Expect(";")
+	}
+	static := p.tokenProcessor.ConsumeKind(token.Static) != nil
 	dec, err := p.declare()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
 	if dec == nil {
+		if static {
+			return nil, p.tokenProcessor.Errorf("expected a type after %q", "static")
+		}
 		return nil, nil
 	}
+	if dec.name == "" {
+		// only declares a struct tag
+		return NodeNop{}, p.tokenProcessor.Expect(";")
+	}
 
-	if err := p.tokenProcessor.Expect("("); err != nil {
+	if p.tokenProcessor.ConsumeReserved("(") {
+		return p.funcDecl(*dec, static)
+	}
+	return p.globalVar(*dec, static)
+}
+
+// typedef parses what follows "typedef" up to the ";", declaring the name as the type.
+func (p *Parser) typedef() error {
+	dec, err := p.declare()
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	if dec == nil {
+		return p.tokenProcessor.Errorf("expected a type after %q", "typedef")
+	}
+	if dec.name == "" {
+		return nil
+	}
+	return p.declareSymbol(symbol{kind: symTypedef, name: dec.name, Type: dec.Type, tok: dec.tok})
+}
+
+func (p *Parser) globalVar(dec declaration, static bool) (Generatable, error) {
+	var init []Generatable
+	if p.tokenProcessor.ConsumeReserved("=") {
+		var err error
+		if dec.Type, init, err = p.initializer(dec.Type); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	}
+	if dec.Type.Kind() == types.Array && dec.Type.Len() == 0 {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "array size missing in %q", dec.name)
+	}
+	if types.Incomplete(dec.Type) {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "storage size of %q isn't known", dec.name)
+	}
+	if err := p.tokenProcessor.Expect(";"); err != nil {
 		return nil, fail.Wrap(err)
 	}
-	args := []Pointable{}
-	for {
-		dec, err := p.declare()
+
+	if err := p.declareSymbol(symbol{kind: symGlobal, name: dec.name, Type: dec.Type, tok: dec.tok}); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	return newGVarDef(dec.tok, dec.name, static, dec.Type, init), nil
+}
+
+// initializer parses the initializer of an object of type t.
+// Arrays take a braced list whose nested lists initialize the sub-arrays,
+// and the result is flattened into one entry per scalar, nil being zero.
+// An array declared without its size gets it from the number of elements.
+func (p *Parser) initializer(t types.Type) (types.Type, []Generatable, error) {
+	if t.Kind().HasMembers() {
+		return nil, nil, p.tokenProcessor.Errorf("initializing %q is not supported", t)
+	}
+	if t.Kind() != types.Array {
+		braced := p.tokenProcessor.ConsumeReserved("{")
+		tok := p.tokenProcessor.Peek()
+		n, err := p.assign()
 		if err != nil {
-			return nil, fail.Wrap(err)
+			return nil, nil, fail.Wrap(err)
 		}
-		if dec == nil {
-			break
+		if n == nil {
+			return nil, nil, p.tokenProcessor.ErrorAt(tok, "expected an initializer")
+		}
+		if braced {
+			if err := p.tokenProcessor.Expect("}"); err != nil {
+				return nil, nil, fail.Wrap(err)
+			}
+		}
+		return t, []Generatable{n}, nil
+	}
+
+	if t.PointingTo().Kind() == types.Char && p.tokenProcessor.NextKind() == token.String {
+		return p.stringInitializer(t)
+	}
+
+	if err := p.tokenProcessor.Expect("{"); err != nil {
+		return nil, nil, fail.Wrap(err)
+	}
+	elems := [][]Generatable{}
+	for !p.tokenProcessor.ConsumeReserved("}") {
+		if len(elems) > 0 {
+			if err := p.tokenProcessor.Expect(","); err != nil {
+				return nil, nil, fail.Wrap(err)
+			}
+			// a trailing comma is allowed
+			if p.tokenProcessor.ConsumeReserved("}") {
+				break
+			}
+		}
+		if t.Len() != 0 && len(elems) == t.Len() {
+			return nil, nil, p.tokenProcessor.Errorf("excess elements in array initializer")
+		}
+		_, elem, err := p.initializer(t.PointingTo())
+		if err != nil {
+			return nil, nil, fail.Wrap(err)
 		}
+		elems = append(elems, elem)
+	}
+
+	if t.Len() == 0 {
+		t = types.ArrayOf(t.PointingTo(), len(elems))
+	}
+	flat := make([]Generatable, 0, scalarCount(t))
+	for _, elem := range elems {
+		flat = append(flat, elem...)
+	}
+	for len(flat) < cap(flat) {
+		flat = append(flat, nil)
+	}
+	return t, flat, nil
+}
+
+// stringInitializer initializes a char array with the bytes of a string literal as in char s[] = "abc".
+func (p *Parser) stringInitializer(t types.Type) (types.Type, []Generatable, error) {
+	tok := p.tokenProcessor.Peek()
+	n, err := p.str()
+	if err != nil {
+		return nil, nil, fail.Wrap(err)
+	}
+	value := n.(*NodeStr).value
+	if t.Len() == 0 {
+		t = types.ArrayOf(t.PointingTo(), len(value)+1)
+	}
+	// the terminating NUL is dropped when it does not fit, as C allows
+	if len(value) > t.Len() {
+		return nil, nil, p.tokenProcessor.ErrorAt(tok, "initializer-string for array of chars is too long")
+	}
+	init := make([]Generatable, t.Len())
+	for i, c := range value {
+		init[i] = newnodeImplNum(tok, int(c))
+	}
+	return t, init, nil
+}
+
+// scalarCount returns how many scalars an object of type t consists of.
+func scalarCount(t types.Type) int {
+	if t.Kind() == types.Array {
+		return t.Len() * scalarCount(t.PointingTo())
+	}
+	return 1
+}
+
+// funcDecl parses the rest of a function prototype or definition, following the "(".
+func (p *Parser) funcDecl(dec declaration, static bool) (Generatable, error) {
+	if dec.Type.Kind().HasMembers() {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "returning %q by value is not supported", dec.Type)
+	}
+	params, variadic, err := p.params()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	sig := &signature{ret: dec.Type, variadic: variadic, unprototyped: len(params) == 0 && !variadic}
+	for _, param := range params {
+		sig.params = append(sig.params, param.Type)
+	}
+	sym := symbol{kind: symFunc, name: dec.name, Type: dec.Type, tok: dec.tok, sig: sig}
+
+	if p.tokenProcessor.ConsumeReserved(";") {
+		return NodeNop{}, p.declareSymbol(sym)
+	}
+	if variadic {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "defining a variadic function is not supported")
+	}
+	// declared before the body, which may call the function recursively
+	sym.defined = true
+	if err := p.declareSymbol(sym); err != nil {
+		return nil, fail.Wrap(err)
+	}
 
-		err = p.declareVar(*dec) // TODO: fix
+	// the parameters are in the same scope as the outermost block of the body
+	p.enterScope()
+	defer p.leaveScope()
+	p.stackSize = 0
+	args := []Pointable{}
+	for i, param := range params {
+		if param.name == "" {
+			return nil, p.tokenProcessor.ErrorAt(param.tok, "parameter name omitted")
+		}
+		var v TypedNode
+		var err error
+		if i < len(registers) {
+			v, err = p.declareVar(param)
+		} else {
+			// the 7th and later are on the stack above the saved rbp and the return address
+			v, err = p.declareLocal(param, -(16 + (i-len(registers))*8))
+		}
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
+		args = append(args, v.(Pointable))
+	}
+
+	brace := p.tokenProcessor.Peek()
+	if !p.tokenProcessor.ConsumeReserved("{") {
+		return nil, p.tokenProcessor.Errorf("expected a function body")
+	}
+	n, err := p.blockItems(brace)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	// keep rsp 16-byte aligned as the ABI requires
+	offset := alignTo(p.stackSize, 16)
+
+	return newNodeFunc(dec.tok, dec.name, static, dec.Type, args, offset, n), nil
+}
 
-		v, err := p.findLocal(dec.name)
+// params parses the parameters of a function up to the closing ")".
+// The names may be omitted as in a prototype, and "..." at the end makes the function variadic.
+func (p *Parser) params() ([]declaration, bool, error) {
+	params := []declaration{}
+	if p.tokenProcessor.ConsumeReserved(")") {
+		return params, false, nil
+	}
+	variadic := false
+	for {
+		if p.tokenProcessor.ConsumeReserved("...") {
+			variadic = true
+			break
+		}
+		tok := p.tokenProcessor.Peek()
+		t, err := p.baseType()
 		if err != nil {
-			return nil, fail.Wrap(err)
+			return nil, false, fail.Wrap(err)
 		}
-		args = append(args, newLValue(v.name, v.offset, v.Type))
+		if t == nil {
+			return nil, false, p.tokenProcessor.Errorf("expected a parameter declaration")
+		}
+		dec := declaration{Type: t, tok: tok}
+		if ident := p.tokenProcessor.ConsumeKind(token.Ident); ident != nil {
+			dec.name, dec.tok = ident.Str, ident
+		}
+		if dec.Type, err = p.arrayDims(t); err != nil {
+			return nil, false, fail.Wrap(err)
+		}
+		if dec.Type.Kind().HasMembers() {
+			return nil, false, p.tokenProcessor.ErrorAt(dec.tok, "passing %q by value is not supported", dec.Type)
+		}
+		// a parameter declared as an array is a pointer
+		dec.Type = types.Decay(dec.Type)
+		params = append(params, dec)
+
 		if !p.tokenProcessor.ConsumeReserved(",") {
 			break
 		}
 	}
 	if err := p.tokenProcessor.Expect(")"); err != nil {
-		return nil, fail.Wrap(err)
-	}
-
-	n, err := p.block()
-	if err != nil {
-		return nil, fail.Wrap(err)
+		return nil, false, fail.Wrap(err)
 	}
-	offset := len(p.locals)*8 + 32 // TODO: not to use magic number
-
-	return newNodeFunc(dec.name, args, offset, n), nil
+	return params, variadic, nil
 }
 
 func match(patterns ...func() (Generatable, error)) (Generatable, error) {
@@ -270,16 +472,25 @@ func (p *Parser) stmt() (Generatable, error) {
 		p.ifstmt,
 		p.whilestmt,
 		p.forstmt,
+		p.switchstmt,
+		p.labeledStmt,
 		p.singleStmt,
 	)
 }
 
 func (p *Parser) block() (Generatable, error) {
+	tok := p.tokenProcessor.Peek()
 	ok := p.tokenProcessor.ConsumeReserved("{")
 	if !ok {
 		return nil, nil
 	}
+	p.enterScope()
+	defer p.leaveScope()
+	return p.blockItems(tok)
+}
 
+// blockItems parses the statements of a block up to the closing "}", the opening one being tok.
+func (p *Parser) blockItems(tok *token.Token) (Generatable, error) {
 	var nodes []Generatable
 
 	for !p.tokenProcessor.ConsumeReserved("}") {
@@ -289,7 +500,7 @@ func (p *Parser) block() (Generatable, error) {
 		}
 		nodes = append(nodes, n)
 	}
-	return NewNodeBlock(nodes), nil
+	return NewNodeBlock(tok, nodes), nil
 }
 
 func (p *Parser) singleStmt() (n Generatable, err error) {
@@ -299,12 +510,26 @@ func (p *Parser) singleStmt() (n Generatable, err error) {
 		}
 	}()
 
-	if p.tokenProcessor.ConsumeReturn() {
+	if tok := p.tokenProcessor.ConsumeKind(token.Return); tok != nil {
 		l, err := p.expr()
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
-		return newReturn(l), nil
+		if l == nil {
+			return nil, p.tokenProcessor.Errorf("expected an expression after %q", "return")
+		}
+		return newReturn(tok, l), nil
+	}
+
+	if p.tokenProcessor.ConsumeKind(token.Typedef) != nil {
+		return NodeNop{}, p.typedef()
+	}
+
+	if tok := p.tokenProcessor.ConsumeKind(token.Break); tok != nil {
+		return p.jump(tok, Break)
+	}
+	if tok := p.tokenProcessor.ConsumeKind(token.Continue); tok != nil {
+		return p.jump(tok, Continue)
 	}
 
 	{
@@ -312,61 +537,330 @@ func (p *Parser) singleStmt() (n Generatable, err error) {
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
+		if dec != nil && dec.name == "" {
+			return NodeNop{}, nil
+		}
 		if dec != nil {
-			p.declareVar(*dec)
-			return nopNode{}, nil
+			v, err := p.declareVar(*dec)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			return newLVarDef(dec.tok, v.(*NodeLValue)), nil
 		}
 	}
 
-	n, err = p.expr()
+	tok := p.tokenProcessor.Peek()
+	e, err := p.expr()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
-	return n, nil
+	if e == nil {
+		// an empty statement, or something which is not a statement and fails on the missing ";"
+		return NodeNop{}, nil
+	}
+	return newExprStmt(tok, e), nil
 }
 
 func (p *Parser) declare() (*declaration, error) {
+	tok := p.tokenProcessor.Peek()
+	t, err := p.baseType()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if t == nil {
+		return nil, nil
+	}
+
+	// func or var
+	ident := p.tokenProcessor.ConsumeKind(token.Ident)
+	if ident == nil {
+		// struct S { ... }; declares nothing but the type, which is left nameless
+		if p.tokenProcessor.NextStr() == ";" {
+			return &declaration{Type: t, tok: tok}, nil
+		}
+		return nil, p.tokenProcessor.Errorf("expected an identifier")
+	}
+
+	t, err = p.arrayDims(t)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	return &declaration{name: ident.Str, Type: t, tok: ident}, nil
+}
+
+// baseType parses a type specifier followed by "*"s, and returns nil if there is none.
+func (p *Parser) baseType() (types.Type, error) {
+	t, err := p.typeSpecifier()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if t == nil {
+		return nil, nil
+	}
+	for p.tokenProcessor.ConsumeReserved("*") {
+		t = types.PointingTo(t)
+	}
+	return t, nil
+}
+
+func (p *Parser) typeSpecifier() (types.Type, error) {
+	for _, k := range []types.Kind{types.Struct, types.Union} {
+		if p.tokenProcessor.ConsumeReserved(k.Identifier()) {
+			return p.recordSpecifier(k)
+		}
+	}
+	if p.tokenProcessor.ConsumeReserved("enum") {
+		return p.enumSpecifier()
+	}
 	for _, k := range types.All() {
-		if !p.tokenProcessor.ConsumeReserved(k.Identifier()) {
-			continue
+		if p.tokenProcessor.ConsumeReserved(k.Identifier()) {
+			return k.Type(), nil
+		}
+	}
+	return p.typedefName(), nil
+}
+
+// recordSpecifier parses what follows "struct" or "union", which is k: a tag, a member list or both.
+// A tag refers to the type defined with it, which may be defined later as in struct node *next.
+func (p *Parser) recordSpecifier(k types.Kind) (types.Type, error) {
+	newType := types.StructOf
+	if k == types.Union {
+		newType = types.UnionOf
+	}
+
+	tag := p.tokenProcessor.ConsumeKind(token.Ident)
+	if !p.tokenProcessor.ConsumeReserved("{") {
+		if tag == nil {
+			return nil, p.tokenProcessor.Errorf("expected a %s tag or a member list", k.Identifier())
+		}
+		if t, ok := p.lookupTag(tag.Str); ok {
+			return t, p.checkTag(tag, t, k)
 		}
-		t := k.Type()
+		t := newType(tag.Str)
+		p.declareTag(tag.Str, t)
+		return t, nil
+	}
 
-		for p.tokenProcessor.ConsumeReserved("*") {
-			t = types.PointingTo(t)
+	// a definition in an inner scope introduces a new type even if the tag is used outside
+	var t types.Type
+	switch existing, ok := p.innermost().tags[tagName(tag)]; {
+	case tag == nil:
+		t = newType("")
+	case ok && !types.Incomplete(existing):
+		return nil, p.tokenProcessor.ErrorAt(tag, "redefinition of %q", existing)
+	case ok:
+		if err := p.checkTag(tag, existing, k); err != nil {
+			return nil, fail.Wrap(err)
 		}
+		t = existing
+	default:
+		// registered before the members, which may point to the type itself
+		t = newType(tag.Str)
+		p.declareTag(tag.Str, t)
+	}
+
+	members, err := p.members()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	types.SetMembers(t, members)
+	return t, nil
+}
 
-		// func or var
-		identName, ok := p.tokenProcessor.ConsumeIdent()
+// enumSpecifier parses what follows "enum". An enum is an int, and its enumerators are int constants.
+func (p *Parser) enumSpecifier() (types.Type, error) {
+	t := types.NewInt()
+	tag := p.tokenProcessor.ConsumeKind(token.Ident)
+	if !p.tokenProcessor.ConsumeReserved("{") {
+		if tag == nil {
+			return nil, p.tokenProcessor.Errorf("expected an enum tag or a list of enumerators")
+		}
+		existing, ok := p.lookupTag(tag.Str)
 		if !ok {
-			return nil, fail.New("Expected identifier")
+			return nil, p.tokenProcessor.ErrorAt(tag, "use of undeclared enum %q", tag.Str)
+		}
+		return t, p.checkTag(tag, existing, types.Int)
+	}
+	if tag != nil {
+		if existing, ok := p.innermost().tags[tag.Str]; ok {
+			if err := p.checkTag(tag, existing, types.Int); err != nil {
+				return nil, fail.Wrap(err)
+			}
+			return nil, p.tokenProcessor.ErrorAt(tag, "redefinition of %q", "enum "+tag.Str)
 		}
-		return &declaration{name: identName, Type: t}, nil
+		p.declareTag(tag.Str, t)
 	}
 
-	return nil, nil
+	val := 0
+	for {
+		tok := p.tokenProcessor.ConsumeKind(token.Ident)
+		if tok == nil {
+			return nil, p.tokenProcessor.Errorf("expected an enumerator")
+		}
+		if p.tokenProcessor.ConsumeReserved("=") {
+			var err error
+			if val, err = p.constExpr(); err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+		if err := p.declareSymbol(symbol{kind: symConst, name: tok.Str, Type: t, tok: tok, value: val}); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		val++
+
+		// a trailing comma is allowed
+		if !p.tokenProcessor.ConsumeReserved(",") || p.tokenProcessor.NextStr() == "}" {
+			break
+		}
+	}
+	if err := p.tokenProcessor.Expect("}"); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	return t, nil
 }
 
-func (p *Parser) declareVar(dec declaration) error {
-	_, exists := p.locals[dec.name]
-	if exists {
-		return fail.Errorf("Variable with name %q has already been declared", dec.name)
+// checkTag reports an error unless the tag, which was declared as t, is used for a type of kind k.
+// Enums are recorded as int.
+func (p *Parser) checkTag(tag *token.Token, t types.Type, k types.Kind) error {
+	if t.Kind() != k {
+		return p.tokenProcessor.ErrorAt(tag, "use of %q with tag type that does not match previous declaration", tag.Str)
 	}
+	return nil
+}
 
-	totalOffset := 0
-	for _, local := range p.locals {
-		totalOffset += local.size
+func tagName(tag *token.Token) string {
+	if tag == nil {
+		return ""
 	}
+	return tag.Str
+}
 
-	n := lvar{offset: totalOffset + 8, size: 8, name: dec.name, Type: dec.Type}
-	p.locals[dec.name] = n
+// constExpr parses an integer constant expression and evaluates it.
+func (p *Parser) constExpr() (int, error) {
+	tok := p.tokenProcessor.Peek()
+	n, err := p.assign()
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	if n == nil {
+		return 0, p.tokenProcessor.Errorf("expected an expression")
+	}
+	e, err := (&checker{}).expr(n)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	v, ok := constValue(e)
+	if !ok || !e.Type().Kind().IsInteger() {
+		return 0, p.tokenProcessor.ErrorAt(tok, "expression is not an integer constant expression")
+	}
+	return v, nil
+}
 
-	return nil
+// members parses the member declarations of a struct up to the closing "}".
+func (p *Parser) members() ([]types.Member, error) {
+	members := []types.Member{}
+	names := map[string]bool{}
+	for !p.tokenProcessor.ConsumeReserved("}") {
+		dec, err := p.declare()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if dec == nil {
+			return nil, p.tokenProcessor.Errorf("expected a member declaration")
+		}
+		if dec.name == "" {
+			return nil, p.tokenProcessor.ErrorAt(dec.tok, "declaration does not declare anything")
+		}
+		if names[dec.name] {
+			return nil, p.tokenProcessor.ErrorAt(dec.tok, "duplicate member %q", dec.name)
+		}
+		if types.Incomplete(dec.Type) || dec.Type.Kind() == types.Array && dec.Type.Len() == 0 {
+			return nil, p.tokenProcessor.ErrorAt(dec.tok, "field %q has incomplete type", dec.name)
+		}
+		if err := p.tokenProcessor.Expect(";"); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		names[dec.name] = true
+		members = append(members, types.Member{Name: dec.name, Type: dec.Type})
+	}
+	if len(members) == 0 {
+		return nil, p.tokenProcessor.Errorf("struct has no members")
+	}
+	return members, nil
+}
+
+// typeName parses a type without a declared name, as in sizeof(int[3]).
+func (p *Parser) typeName() (types.Type, error) {
+	t, err := p.baseType()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if t == nil {
+		return nil, nil
+	}
+	return p.arrayDims(t)
+}
+
+// arrayDims parses trailing "[N]"s. int a[2][3] is an array of 2 arrays of 3 ints.
+// The size of the outermost dimension may be omitted, leaving a length of 0.
+func (p *Parser) arrayDims(base types.Type) (types.Type, error) {
+	if !p.tokenProcessor.ConsumeReserved("[") {
+		return base, nil
+	}
+	length := 0
+	if !p.tokenProcessor.ConsumeReserved("]") {
+		tok := p.tokenProcessor.Peek()
+		var err error
+		if length, err = p.constExpr(); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if length <= 0 {
+			return nil, p.tokenProcessor.ErrorAt(tok, "array size must be positive")
+		}
+		if err := p.tokenProcessor.Expect("]"); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	}
+	elem, err := p.arrayDims(base)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if elem.Kind() == types.Array && elem.Len() == 0 {
+		return nil, p.tokenProcessor.Errorf("only the first array dimension may be omitted")
+	}
+	return types.ArrayOf(elem, length), nil
+}
+
+// declareVar declares a local variable in the innermost scope, and returns a reference to it.
+// A variable which shadows another gets a stack slot of its own.
+func (p *Parser) declareVar(dec declaration) (TypedNode, error) {
+	if dec.Type.Kind() == types.Array && dec.Type.Len() == 0 {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "array size missing in %q", dec.name)
+	}
+	if types.Incomplete(dec.Type) {
+		return nil, p.tokenProcessor.ErrorAt(dec.tok, "storage size of %q isn't known", dec.name)
+	}
+
+	offset := alignTo(p.stackSize+dec.Type.Size(), dec.Type.Align())
+	v, err := p.declareLocal(dec, offset)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	p.stackSize = offset
+	return v, nil
+}
 
+// declareLocal declares a local variable placed offset bytes below rbp.
+func (p *Parser) declareLocal(dec declaration, offset int) (TypedNode, error) {
+	if err := p.declareSymbol(symbol{kind: symLocal, name: dec.name, Type: dec.Type, tok: dec.tok, offset: offset}); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	return newLValue(dec.tok, dec.name, offset, dec.Type), nil
 }
 
 func (p *Parser) ifstmt() (Generatable, error) {
-	if t := p.tokenProcessor.ConsumeKind(token.If); t == nil {
+	tok := p.tokenProcessor.ConsumeKind(token.If)
+	if tok == nil {
 		return nil, nil
 	}
 	if err := p.tokenProcessor.Expect("("); err != nil {
@@ -377,6 +871,9 @@ func (p *Parser) ifstmt() (Generatable, error) {
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
+	if condition == nil {
+		return nil, p.tokenProcessor.Errorf("expected an expression")
+	}
 	if err := p.tokenProcessor.Expect(")"); err != nil {
 		return nil, fail.Wrap(err)
 	}
@@ -385,7 +882,7 @@ func (p *Parser) ifstmt() (Generatable, error) {
 		return nil, fail.Wrap(err)
 	}
 
-	var secondStmt Generatable = nopNode{}
+	var secondStmt Generatable = NodeNop{}
 	if t := p.tokenProcessor.ConsumeKind(token.Else); t != nil {
 		var err error
 		secondStmt, err = p.stmt()
@@ -394,11 +891,12 @@ func (p *Parser) ifstmt() (Generatable, error) {
 		}
 	}
 
-	return newIf(condition, firstStmt, secondStmt), nil
+	return newIf(tok, condition, firstStmt, secondStmt), nil
 }
 
 func (p *Parser) whilestmt() (Generatable, error) {
-	if t := p.tokenProcessor.ConsumeKind(token.While); t == nil {
+	tok := p.tokenProcessor.ConsumeKind(token.While)
+	if tok == nil {
 		return nil, nil
 	}
 
@@ -410,19 +908,25 @@ func (p *Parser) whilestmt() (Generatable, error) {
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
+	if condition == nil {
+		return nil, p.tokenProcessor.Errorf("expected an expression")
+	}
 	if err := p.tokenProcessor.Expect(")"); err != nil {
 		return nil, fail.Wrap(err)
 	}
+	labels := p.enterLoop(".Lbegin")
+	defer p.leaveJumpTarget()
 	stmt, err := p.stmt()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
 
-	return newWhile(condition, stmt), nil
+	return newWhile(tok, condition, stmt, labels), nil
 }
 
 func (p *Parser) forstmt() (Generatable, error) {
-	if t := p.tokenProcessor.ConsumeKind(token.For); t == nil {
+	tok := p.tokenProcessor.ConsumeKind(token.For)
+	if tok == nil {
 		return nil, nil
 	}
 
@@ -469,16 +973,142 @@ func (p *Parser) forstmt() (Generatable, error) {
 		}
 	}
 
+	labels := p.enterLoop(".Lcontinue")
+	defer p.leaveJumpTarget()
+	stmt, err := p.stmt()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+
+	return newFor(tok, init, condition, update, stmt, labels), nil
+}
+
+func (p *Parser) switchstmt() (Generatable, error) {
+	tok := p.tokenProcessor.ConsumeKind(token.Switch)
+	if tok == nil {
+		return nil, nil
+	}
+
+	if err := p.tokenProcessor.Expect("("); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	condition, err := p.expr()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if condition == nil {
+		return nil, p.tokenProcessor.Errorf("expected an expression")
+	}
+	if err := p.tokenProcessor.Expect(")"); err != nil {
+		return nil, fail.Wrap(err)
+	}
+
+	n := newSwitch(tok, p.enterSwitch())
+	defer p.leaveJumpTarget()
+	p.switches = append(p.switches, n)
+	defer func() { p.switches = p.switches[:len(p.switches)-1] }()
+
 	stmt, err := p.stmt()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
+	n.condition, n.stmt = condition, stmt
+	return n, nil
+}
+
+// labeledStmt parses a case or default label and the statement following it,
+// registering the label with the innermost switch.
+func (p *Parser) labeledStmt() (Generatable, error) {
+	var n *NodeCase
+	if tok := p.tokenProcessor.ConsumeKind(token.Case); tok != nil {
+		v, err := p.constExpr()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		// the controlling expression is compared as an int
+		n = newCase(tok, false, int(int32(v)))
+	} else if tok := p.tokenProcessor.ConsumeKind(token.Default); tok != nil {
+		n = newCase(tok, true, 0)
+	} else {
+		return nil, nil
+	}
+
+	if len(p.switches) == 0 {
+		return nil, p.tokenProcessor.ErrorAt(n.tok, "%q label not within a switch statement", n.tok.Str)
+	}
+	sw := p.switches[len(p.switches)-1]
+	if n.isDefault {
+		if sw.defaultCase != nil {
+			return nil, p.tokenProcessor.ErrorAt(n.tok, "multiple default labels in one switch")
+		}
+		sw.defaultCase = n
+	} else {
+		for _, c := range sw.cases {
+			if c.value == n.value {
+				return nil, p.tokenProcessor.ErrorAt(n.tok, "duplicate case value %d", n.value)
+			}
+		}
+		sw.cases = append(sw.cases, n)
+	}
 
-	return newFor(init, condition, update, stmt), nil
+	if err := p.tokenProcessor.Expect(":"); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	stmt, err := p.stmt()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	n.stmt = stmt
+	return n, nil
 }
 
 func (p *Parser) expr() (TypedNode, error) {
-	return p.assign()
+	node, err := p.assign()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+
+	for {
+		tok := p.tokenProcessor.Peek()
+		if !p.tokenProcessor.ConsumeReserved(",") {
+			return node, nil
+		}
+		r, err := p.assign()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if node == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", tok.Str)
+		}
+		node = newComma(tok, node, r)
+	}
+}
+
+// conditional parses cond ? a : b, where a may be any expression and b is another conditional.
+func (p *Parser) conditional() (TypedNode, error) {
+	node, err := p.logOr()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	tok := p.tokenProcessor.Peek()
+	if !p.tokenProcessor.ConsumeReserved("?") {
+		return node, nil
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if err := p.tokenProcessor.Expect(":"); err != nil {
+		return nil, fail.Wrap(err)
+	}
+	els, err := p.conditional()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if node == nil || then == nil || els == nil {
+		return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression around %q", tok.Str)
+	}
+	return newTernary(tok, node, then, els), nil
 }
 
 func (p *Parser) primary() (TypedNode, error) {
@@ -504,7 +1134,12 @@ func (p *Parser) primary() (TypedNode, error) {
 		}
 	}
 
+	if n, err := p.str(); n != nil || err != nil {
+		return n, err
+	}
+
 	// そうでなければ数値のはず
+	tok := p.tokenProcessor.Peek()
 	i, ok, err := p.tokenProcessor.ConsumeNum()
 	if err != nil {
 		return nil, fail.Wrap(err)
@@ -512,24 +1147,46 @@ func (p *Parser) primary() (TypedNode, error) {
 	if !ok {
 		return nil, nil
 	}
-	return newnodeImplNum(i), nil
+	return newnodeImplNum(tok, i), nil
+}
+
+// str parses a string literal. Adjacent literals are concatenated as in "ab" "c".
+func (p *Parser) str() (TypedNode, error) {
+	first := p.tokenProcessor.ConsumeKind(token.String)
+	if first == nil {
+		return nil, nil
+	}
+	value := []byte{}
+	for tok := first; tok != nil; tok = p.tokenProcessor.ConsumeKind(token.String) {
+		v, err := token.Unquote(tok.Str)
+		if err != nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "%s", err.Error())
+		}
+		value = append(value, v...)
+	}
+	return newStr(first, value), nil
 }
 
 // parse func or var
 func (p *Parser) resolveIdent() (TypedNode, error) {
-	ident, ok := p.tokenProcessor.ConsumeIdent()
-	if !ok {
+	tok := p.tokenProcessor.ConsumeKind(token.Ident)
+	if tok == nil {
 		return nil, nil
 	}
+	ident := tok.Str
 
 	// if function
 	if p.tokenProcessor.ConsumeReserved("(") {
 		args := []Generatable{}
 		for {
-			arg, err := p.expr()
+			// not expr, whose comma would swallow the next argument
+			arg, err := p.assign()
 			if err != nil {
 				return nil, fail.Wrap(err)
 			}
+			if arg == nil && len(args) > 0 {
+				return nil, p.tokenProcessor.Errorf("expected an expression")
+			}
 			if arg == nil {
 				break
 			}
@@ -539,65 +1196,152 @@ func (p *Parser) resolveIdent() (TypedNode, error) {
 			}
 		}
 
-		n := newFuncCall(ident, types.NewInt(), args) // FIXME: not int
-		// TODO: parse args
 		if err := p.tokenProcessor.Expect(")"); err != nil {
 			return nil, fail.Wrap(err)
 		}
-		return n, nil
-	}
 
-	// if not function, should be a var
-	v, err := p.findLocal(ident)
-	if err != nil {
-		return nil, fail.Wrap(err)
+		sym, ok := p.lookup(ident)
+		if !ok {
+			// implicitly declared, which is checked against a later definition if any
+			return newFuncCall(tok, ident, nil, args), nil
+		}
+		if sym.kind != symFunc {
+			return nil, p.tokenProcessor.ErrorAt(tok, "called object %q is not a function", ident)
+		}
+		return newFuncCall(tok, ident, sym.sig, args), nil
 	}
 
-	return newLValue(v.name, v.offset, v.Type), nil
+	// if not function, should be a var
+	return p.findVar(tok)
 }
 
-func (p *Parser) findLocal(str string) (lvar, error) {
-	v, ok := p.locals[str]
+// findVar resolves a variable or an enumerator declared in the innermost scope which has the name.
+func (p *Parser) findVar(tok *token.Token) (TypedNode, error) {
+	sym, ok := p.lookup(tok.Str)
 	if !ok {
-		return lvar{}, fail.Errorf("Use of undeclared variable %q", str)
+		return nil, p.tokenProcessor.ErrorAt(tok, "use of undeclared variable %q", tok.Str)
 	}
-	return v, nil
+	switch sym.kind {
+	case symLocal:
+		return newLValue(tok, sym.name, sym.offset, sym.Type), nil
+	case symGlobal:
+		return newGVar(tok, sym.name, sym.Type), nil
+	case symConst:
+		return newnodeImplNum(tok, sym.value), nil
+	case symTypedef:
+		return nil, p.tokenProcessor.ErrorAt(tok, "unexpected type name %q: expected expression", tok.Str)
+	}
+	return nil, p.tokenProcessor.ErrorAt(tok, "function %q cannot be used as a value", tok.Str)
 }
 
-func (p *Parser) resetLocal() {
-	p.locals = map[string]lvar{}
+func alignTo(n, align int) int {
+	return (n + align - 1) / align * align
 }
 
 func (p *Parser) assign() (TypedNode, error) {
-	n, err := p.equality()
+	n, err := p.conditional()
 	if err != nil {
 		return nil, fail.Wrap(err)
 	}
-	if p.tokenProcessor.ConsumeReserved("=") {
+	if tok := p.tokenProcessor.Peek(); p.tokenProcessor.ConsumeReserved("=") {
+		r, err := p.assign()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", "=")
+		}
+		return newAssign(tok, n, r), nil
+	}
+	if tok := p.tokenProcessor.Peek(); compoundOps[tok.Str] != 0 && p.tokenProcessor.ConsumeReserved(tok.Str) {
 		r, err := p.assign()
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
-		return newAssign(n, r), nil
+		if n == nil || r == nil {
+			return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression on both sides of %q", tok.Str)
+		}
+		return newCompoundAssign(tok, compoundOps[tok.Str], n, r, false), nil
 	}
 
 	return n, nil
 }
 
+// compoundOps maps an assignment operator such as "+=" to the binary operator it applies.
+var compoundOps = map[string]Kind{
+	"+=":  Add,
+	"-=":  Sub,
+	"*=":  Mul,
+	"/=":  Div,
+	"%=":  Mod,
+	"&=":  BitAnd,
+	"|=":  BitOr,
+	"^=":  BitXor,
+	"<<=": Shl,
+	">>=": Shr,
+}
+
+// incDecOps maps "++" and "--" to the binary operator they apply with 1.
+var incDecOps = map[string]Kind{
+	"++": Add,
+	"--": Sub,
+}
+
 func (p *Parser) unary() (TypedNode, error) {
+	tok := p.tokenProcessor.Peek()
+	if op := incDecOps[tok.Str]; op != 0 && p.tokenProcessor.ConsumeReserved(tok.Str) {
+		// ++a is a += 1
+		n, err := p.unary()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf("expected an expression after %q", tok.Str)
+		}
+		return newCompoundAssign(tok, op, n, newnodeImplNum(tok, 1), false), nil
+	}
+	if p.tokenProcessor.ConsumeKind(token.Sizeof) != nil {
+		return p.sizeof(tok)
+	}
 	if p.tokenProcessor.ConsumeReserved("+") {
-		n, err := p.primary()
+		n, err := p.unary()
 		if err != nil {
 			return nil, fail.Wrap(err)
 		}
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "+"`)
+		}
 		return n, nil
 	}
 	if p.tokenProcessor.ConsumeReserved("-") {
-		n, err := p.primary()
+		n, err := p.unary()
 		if err != nil {
-			return nil, err
+			return nil, fail.Wrap(err)
 		}
-		return newBinaryOperator(Sub, newnodeImplNum(0), n), nil
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "-"`)
+		}
+		return newBinaryOperator(tok, Sub, newnodeImplNum(tok, 0), n), nil
+	}
+	if p.tokenProcessor.ConsumeReserved("!") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "!"`)
+		}
+		return newUnaryOperator(tok, Not, n), nil
+	}
+	if p.tokenProcessor.ConsumeReserved("~") {
+		n, err := p.unary()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "~"`)
+		}
+		return newUnaryOperator(tok, BitNot, n), nil
 	}
 	if p.tokenProcessor.ConsumeReserved("&") {
 		n, err := p.unary()
@@ -605,19 +1349,99 @@ func (p *Parser) unary() (TypedNode, error) {
 			return nil, err
 		}
 		if n == nil {
-			return nil, fail.New(`Non nil node required after "&"`)
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "&"`)
 		}
-		return newNodeAddr(n), nil
+		return newNodeAddr(tok, n), nil
 	}
 	if p.tokenProcessor.ConsumeReserved("*") {
 		n, err := p.unary()
 		if err != nil {
 			return nil, err
 		}
-		return newNodeDeref(n), nil
+		if n == nil {
+			return nil, p.tokenProcessor.Errorf(`expected an expression after "*"`)
+		}
+		return newNodeDeref(tok, n), nil
+	}
+
+	return p.postfix()
+}
+
+func (p *Parser) sizeof(tok *token.Token) (TypedNode, error) {
+	// sizeof(type) or sizeof followed by an expression, which may start with "(" as well
+	if paren := p.tokenProcessor.Peek(); p.tokenProcessor.ConsumeReserved("(") {
+		t, err := p.typeName()
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if t != nil {
+			if err := p.tokenProcessor.Expect(")"); err != nil {
+				return nil, fail.Wrap(err)
+			}
+			return newSizeofType(tok, t), nil
+		}
+		p.tokenProcessor.Rewind(paren)
 	}
 
-	return p.primary()
+	n, err := p.unary()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if n == nil {
+		return nil, p.tokenProcessor.Errorf(`expected an expression after "sizeof"`)
+	}
+	return newSizeofExpr(tok, n), nil
+}
+
+func (p *Parser) postfix() (TypedNode, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+
+	for {
+		tok := p.tokenProcessor.Peek()
+		if p.tokenProcessor.ConsumeReserved("[") {
+			// a[i] is *(a + i)
+			idx, err := p.expr()
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			if n == nil || idx == nil {
+				return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression around %q", "[")
+			}
+			if err := p.tokenProcessor.Expect("]"); err != nil {
+				return nil, fail.Wrap(err)
+			}
+			n = newNodeDeref(tok, newBinaryOperator(tok, Add, n, idx))
+			continue
+		}
+		if p.tokenProcessor.ConsumeReserved(".") {
+			name, err := p.memberName(tok, n)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			n = newMember(tok, n, name)
+			continue
+		}
+		if p.tokenProcessor.ConsumeReserved("->") {
+			// p->m is (*p).m
+			name, err := p.memberName(tok, n)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			n = newMember(tok, newNodeDeref(tok, n), name)
+			continue
+		}
+		if op := incDecOps[tok.Str]; op != 0 && p.tokenProcessor.ConsumeReserved(tok.Str) {
+			if n == nil {
+				return nil, p.tokenProcessor.ErrorAt(tok, "expected an expression before %q", tok.Str)
+			}
+			n = newCompoundAssign(tok, op, n, newnodeImplNum(tok, 1), true)
+			continue
+		}
+		return n, nil
+	}
 }
 
 func (p *Parser) Parse() ([]Generatable, error) {
@@ -628,3 +1452,15 @@ func (p *Parser) Parse() ([]Generatable, error) {
 
 	return nodes, nil
 }
+
+// memberName parses the name following "." or "->", which is tok, applied to n.
+func (p *Parser) memberName(tok *token.Token, n TypedNode) (string, error) {
+	if n == nil {
+		return "", p.tokenProcessor.ErrorAt(tok, "expected an expression before %q", tok.Str)
+	}
+	name, ok := p.tokenProcessor.ConsumeIdent()
+	if !ok {
+		return "", p.tokenProcessor.Errorf("expected a member name after %q", tok.Str)
+	}
+	return name, nil
+}
@@ -3,20 +3,23 @@ package node
 import (
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
 
-type nodeReturn struct {
+type NodeReturn struct {
+	tok *token.Token
 	val Generatable
 }
 
-func newReturn(val Generatable) Generatable {
-	return &nodeReturn{
+func newReturn(tok *token.Token, val Generatable) Generatable {
+	return &NodeReturn{
+		tok: tok,
 		val: val,
 	}
 }
 
-func (n *nodeReturn) Generate() (string, error) {
+func (n *NodeReturn) Generate() (string, error) {
 	l, err := n.val.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
@@ -32,3 +35,19 @@ func (n *nodeReturn) Generate() (string, error) {
 	}
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeReturn) Kind() Kind {
+	return Return
+}
+
+func (n *NodeReturn) Value() Generatable {
+	return n.val
+}
+
+func (n *NodeReturn) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeReturn) dump() *tree {
+	return newTree(Return).withNode("value", n.val)
+}
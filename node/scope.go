@@ -0,0 +1,124 @@
+package node
+
+import (
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+)
+
+// symbolKind tells what an identifier is declared as.
+type symbolKind int
+
+const (
+	_ symbolKind = iota
+	symLocal
+	symGlobal
+	symFunc
+	symTypedef
+	symConst
+)
+
+// symbol is a declaration of an ordinary identifier.
+type symbol struct {
+	kind symbolKind
+	name string
+	// the type of a variable, the return type of a function, or what a typedef name stands for
+	Type types.Type
+	tok  *token.Token
+	// the distance of a local variable below rbp
+	offset int
+	// the value of an enumerator
+	value int
+	// the parameters and return type of a function, and whether its body has been seen
+	sig     *signature
+	defined bool
+}
+
+// scope holds the names declared in a block, the outermost one being the file scope.
+// Tags of structs, unions and enums live in a namespace of their own.
+type scope struct {
+	symbols map[string]symbol
+	tags    map[string]types.Type
+}
+
+func newScope() *scope {
+	return &scope{symbols: map[string]symbol{}, tags: map[string]types.Type{}}
+}
+
+func (p *Parser) enterScope() {
+	p.scopes = append(p.scopes, newScope())
+}
+
+func (p *Parser) leaveScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+func (p *Parser) innermost() *scope {
+	return p.scopes[len(p.scopes)-1]
+}
+
+// lookup finds the declaration of name in the innermost scope which has one.
+func (p *Parser) lookup(name string) (symbol, bool) {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if s, ok := p.scopes[i].symbols[name]; ok {
+			return s, true
+		}
+	}
+	return symbol{}, false
+}
+
+func (p *Parser) lookupTag(name string) (types.Type, bool) {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if t, ok := p.scopes[i].tags[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// declareSymbol adds sym to the innermost scope.
+// A name may shadow one of an outer scope, but may not be declared twice in the same scope,
+// except that a typedef may be repeated for the same type,
+// and a function may be declared any number of times with the same signature but defined once.
+func (p *Parser) declareSymbol(sym symbol) error {
+	if existing, ok := p.innermost().symbols[sym.name]; ok {
+		if existing.kind != sym.kind {
+			return p.tokenProcessor.ErrorAt(sym.tok, "redefinition of %q as different kind of symbol", sym.name)
+		}
+		if sym.kind == symTypedef && types.Same(existing.Type, sym.Type) {
+			return nil
+		}
+		if sym.kind == symFunc && !existing.sig.same(sym.sig) {
+			return p.tokenProcessor.ErrorAt(sym.tok, "conflicting types for %q", sym.name)
+		}
+		if sym.kind == symFunc && !(existing.defined && sym.defined) {
+			sym.defined = existing.defined || sym.defined
+			if sym.sig.unprototyped {
+				// a prototype seen earlier still applies
+				sym.sig = existing.sig
+			}
+			p.innermost().symbols[sym.name] = sym
+			return nil
+		}
+		return p.tokenProcessor.ErrorAt(sym.tok, "redefinition of %q", sym.name)
+	}
+	p.innermost().symbols[sym.name] = sym
+	return nil
+}
+
+func (p *Parser) declareTag(name string, t types.Type) {
+	p.innermost().tags[name] = t
+}
+
+// typedefName returns the type named by the identifier at the head of the input, if it is a typedef name.
+func (p *Parser) typedefName() types.Type {
+	tok := p.tokenProcessor.Peek()
+	if tok.Kind != token.Ident {
+		return nil
+	}
+	sym, ok := p.lookup(tok.Str)
+	if !ok || sym.kind != symTypedef {
+		return nil
+	}
+	p.tokenProcessor.ConsumeKind(token.Ident)
+	return sym.Type
+}
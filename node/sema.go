@@ -0,0 +1,599 @@
+package node
+
+import (
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// checker is the semantic analysis pass which runs between parsing and code generation.
+// It gives every expression a type, rejects ill-typed programs,
+// and rewrites pointer arithmetic so that the code generator only sees byte offsets.
+type checker struct {
+	funcs map[string]*NodeFunc
+	fn    *NodeFunc
+}
+
+// Check type-checks the nodes returned by Parser.Parse in place.
+func Check(nodes []Generatable) error {
+	c := &checker{funcs: map[string]*NodeFunc{}}
+	for _, n := range nodes {
+		f, ok := n.(*NodeFunc)
+		if !ok {
+			continue
+		}
+		if _, exists := c.funcs[f.name]; exists {
+			return f.tok.Errorf("redefinition of %q", f.name)
+		}
+		c.funcs[f.name] = f
+	}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *NodeFunc:
+			c.fn = n
+			body, err := c.stmt(n.block)
+			if err != nil {
+				return fail.Wrap(err)
+			}
+			n.block = body
+		case *NodeGVarDef:
+			if err := c.globalVar(n); err != nil {
+				return fail.Wrap(err)
+			}
+		default:
+			return fail.Errorf("Unexpected top-level node %T", n)
+		}
+	}
+	return nil
+}
+
+// globalVar evaluates the initializer of a global variable, which has to be known at compile time.
+func (c *checker) globalVar(n *NodeGVarDef) error {
+	if n.init == nil {
+		return nil
+	}
+	t := scalarType(n.t)
+	n.values = make([]int, len(n.init))
+	for i, g := range n.init {
+		if g == nil {
+			continue
+		}
+		e, err := c.expr(g)
+		if err != nil {
+			return fail.Wrap(err)
+		}
+		if !assignable(t, e) {
+			return n.tok.Errorf("incompatible types when initializing type %q using type %q", t, types.Decay(e.Type()))
+		}
+		v, ok := constValue(e)
+		if !ok {
+			return n.tok.Errorf("initializer element of %q is not a compile-time constant", n.name)
+		}
+		n.init[i], n.values[i] = e, v
+	}
+	return nil
+}
+
+// constValue evaluates a checked integer constant expression.
+func constValue(g Generatable) (int, bool) {
+	switch n := g.(type) {
+	case *NodeNum:
+		return n.val, true
+	case *NodeSizeof:
+		return n.of.Size(), true
+	case *NodeBinaryOperator:
+		if n.t.Kind() == types.Pointer {
+			return 0, false
+		}
+		l, ok := constValue(n.lhs)
+		if !ok {
+			return 0, false
+		}
+		r, ok := constValue(n.rhs)
+		if !ok {
+			return 0, false
+		}
+		switch n.kind {
+		case Add:
+			return l + r, true
+		case Sub:
+			return l - r, true
+		case Mul:
+			return l * r, true
+		case Div:
+			if r == 0 {
+				return 0, false
+			}
+			return l / r, true
+		case Mod:
+			if r == 0 {
+				return 0, false
+			}
+			return l % r, true
+		case BitAnd:
+			return l & r, true
+		case BitOr:
+			return l | r, true
+		case BitXor:
+			return l ^ r, true
+		case Shl:
+			if r < 0 {
+				return 0, false
+			}
+			return l << uint(r), true
+		case Shr:
+			if r < 0 {
+				return 0, false
+			}
+			return l >> uint(r), true
+		case Equal:
+			return boolToInt(l == r), true
+		case NotEqual:
+			return boolToInt(l != r), true
+		case SmallerThan:
+			return boolToInt(l < r), true
+		case SmallerThanOrEqualTo:
+			return boolToInt(l <= r), true
+		case GreaterThan:
+			return boolToInt(l > r), true
+		case GreaterThanOrEqualTo:
+			return boolToInt(l >= r), true
+		}
+	case *NodeTernary:
+		cond, ok := constValue(n.condition)
+		if !ok {
+			return 0, false
+		}
+		if cond != 0 {
+			return constValue(n.then)
+		}
+		return constValue(n.els)
+	case *NodeComma:
+		return 0, false
+	case *NodeLogical:
+		l, ok := constValue(n.lhs)
+		if !ok {
+			return 0, false
+		}
+		r, ok := constValue(n.rhs)
+		if !ok {
+			return 0, false
+		}
+		if n.kind == LogicalAnd {
+			return boolToInt(l != 0 && r != 0), true
+		}
+		return boolToInt(l != 0 || r != 0), true
+	case *NodeUnaryOperator:
+		v, ok := constValue(n.operand)
+		if n.kind == Not {
+			return boolToInt(v == 0), ok
+		}
+		return ^v, ok
+	}
+	return 0, false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c *checker) stmt(g Generatable) (Generatable, error) {
+	var err error
+	switch n := g.(type) {
+	case *NodeBlock:
+		for i, s := range n.stmts {
+			if n.stmts[i], err = c.stmt(s); err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+	case *NodeIf:
+		if n.condition, err = c.scalar(n.condition, n.tok); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.trueStatement, err = c.stmt(n.trueStatement); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.falseStatement, err = c.stmt(n.falseStatement); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeWhile:
+		if n.condition, err = c.scalar(n.condition, n.tok); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.stmt, err = c.stmt(n.stmt); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeFor:
+		if n.init != nil {
+			if n.init, err = c.expr(n.init); err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+		if n.condition != nil {
+			if n.condition, err = c.scalar(n.condition, n.tok); err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+		if n.update != nil {
+			if n.update, err = c.expr(n.update); err != nil {
+				return nil, fail.Wrap(err)
+			}
+		}
+		if n.stmt, err = c.stmt(n.stmt); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeSwitch:
+		if n.condition, err = c.integer(n.condition, n.tok); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.stmt, err = c.stmt(n.stmt); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeCase:
+		if n.stmt, err = c.stmt(n.stmt); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeExprStmt:
+		if n.expr, err = c.expr(n.expr); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeReturn:
+		val, err := c.expr(n.val)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if !assignable(c.fn.ret, val) {
+			return nil, n.tok.Errorf("returning %q from a function with return type %q", types.Decay(val.Type()), c.fn.ret)
+		}
+		n.val = val
+	case NodeNop, *NodeJump, *NodeLVarDef:
+	default:
+		return c.expr(g)
+	}
+	return g, nil
+}
+
+// expr checks g and returns the node which should replace it.
+func (c *checker) expr(g Generatable) (TypedNode, error) {
+	var err error
+	switch n := g.(type) {
+	case *NodeNum, *NodeLValue, *NodeGVar, *NodeStr:
+	case *NodeAddr:
+		operand, err := c.lvalue(n.p.(Generatable))
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if operand == nil {
+			return nil, n.tok.Errorf("cannot take the address of an rvalue")
+		}
+		n.p = operand
+	case *NodeDeref:
+		if n.child, err = c.expr(n.child); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		t := types.Decay(n.child.(Typed).Type())
+		if t.Kind() != types.Pointer {
+			return nil, n.tok.Errorf("indirection requires pointer operand (%q invalid)", t)
+		}
+		n.t = t.PointingTo()
+	case *NodeAssign:
+		lhs, err := c.lvalue(n.lhs.(Generatable))
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if lhs == nil || lhs.Type().Kind() == types.Array {
+			return nil, n.tok.Errorf("expression is not assignable")
+		}
+		rhs, err := c.expr(n.rhs)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if !assignable(lhs.Type(), rhs) {
+			return nil, n.tok.Errorf("incompatible types when assigning to type %q from type %q", lhs.Type(), types.Decay(rhs.Type()))
+		}
+		n.lhs, n.rhs = lhs, rhs
+	case *NodeCompoundAssign:
+		return c.compoundAssign(n)
+	case *NodeTernary:
+		return c.ternary(n)
+	case *NodeComma:
+		if n.lhs, err = c.expr(n.lhs); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.rhs, err = c.expr(n.rhs); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeSizeof:
+		if n.operand != nil {
+			operand, err := c.expr(n.operand)
+			if err != nil {
+				return nil, fail.Wrap(err)
+			}
+			// no decay, sizeof an array is the size of the whole array
+			n.operand, n.of = operand, operand.Type()
+		}
+		if types.Incomplete(n.of) {
+			return nil, n.tok.Errorf("invalid application of sizeof to an incomplete type %q", n.of)
+		}
+	case *NodeMember:
+		of, err := c.lvalue(n.of)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if of == nil {
+			return nil, n.tok.Errorf("member access requires an lvalue")
+		}
+		t := of.Type()
+		if !t.Kind().HasMembers() {
+			return nil, n.tok.Errorf("member reference base type %q is not a structure or union", types.Decay(t))
+		}
+		if types.Incomplete(t) {
+			return nil, n.tok.Errorf("incomplete definition of type %q", t)
+		}
+		m, ok := types.MemberOf(t, n.name)
+		if !ok {
+			return nil, n.tok.Errorf("no member named %q in %q", n.name, t)
+		}
+		n.of, n.member = of, &m
+	case *NodeLogical:
+		if n.lhs, err = c.scalar(n.lhs, n.tok); err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if n.rhs, err = c.scalar(n.rhs, n.tok); err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeUnaryOperator:
+		if n.kind == Not {
+			n.operand, err = c.scalar(n.operand, n.tok)
+		} else {
+			n.operand, err = c.integer(n.operand, n.tok)
+		}
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+	case *NodeBinaryOperator:
+		return c.binaryOperator(n)
+	case *NodeFuncCall:
+		return c.funcCall(n)
+	default:
+		return nil, fail.Errorf("Unexpected expression node %T", g)
+	}
+	return g.(TypedNode), nil
+}
+
+// scalar checks g, whose value is tested against 0 by the operator tok.
+func (c *checker) scalar(g Generatable, tok *token.Token) (TypedNode, error) {
+	n, err := c.expr(g)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if t := types.Decay(n.Type()); !t.Kind().IsInteger() && t.Kind() != types.Pointer {
+		return nil, tok.Errorf("invalid operand to %s (%q)", tok.Str, t)
+	}
+	return n, nil
+}
+
+// integer checks g, which is the operand of the operator tok working on integers only.
+func (c *checker) integer(g Generatable, tok *token.Token) (TypedNode, error) {
+	n, err := c.expr(g)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if t := types.Decay(n.Type()); !t.Kind().IsInteger() {
+		return nil, tok.Errorf("invalid operand to %s (%q)", tok.Str, t)
+	}
+	return n, nil
+}
+
+// lvalue checks g and returns it if it designates an object, nil otherwise.
+func (c *checker) lvalue(g Generatable) (TypedNode, error) {
+	n, err := c.expr(g)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	switch n.(type) {
+	case *NodeLValue, *NodeGVar, *NodeDeref, *NodeStr, *NodeMember:
+		return n, nil
+	}
+	return nil, nil
+}
+
+func (c *checker) binaryOperator(n *NodeBinaryOperator) (TypedNode, error) {
+	lhs, err := c.expr(n.lhs)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	rhs, err := c.expr(n.rhs)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	n.lhs, n.rhs = lhs, rhs
+	lt, rt := types.Decay(lhs.Type()), types.Decay(rhs.Type())
+	invalid := func() error {
+		return n.tok.Errorf("invalid operands to binary %s (%q and %q)", n.tok.Str, lt, rt)
+	}
+
+	switch n.kind {
+	case Add:
+		switch {
+		case lt.Kind().IsInteger() && rt.Kind().IsInteger():
+			n.t = types.NewInt()
+		case lt.Kind() == types.Pointer && rt.Kind().IsInteger():
+			n.rhs = c.scale(n, rhs, lt)
+			n.t = lt
+		case lt.Kind().IsInteger() && rt.Kind() == types.Pointer:
+			// canonicalize to pointer + offset
+			n.lhs, n.rhs = rhs, c.scale(n, lhs, rt)
+			n.t = rt
+		default:
+			return nil, invalid()
+		}
+	case Sub:
+		switch {
+		case lt.Kind().IsInteger() && rt.Kind().IsInteger():
+			n.t = types.NewInt()
+		case lt.Kind() == types.Pointer && rt.Kind().IsInteger():
+			n.rhs = c.scale(n, rhs, lt)
+			n.t = lt
+		case lt.Kind() == types.Pointer && types.Same(lt, rt):
+			// the distance is counted in elements, not bytes
+			n.t = types.NewInt()
+			size := newnodeImplNum(n.tok, lt.PointingTo().Size())
+			div := newBinaryOperator(n.tok, Div, n, size).(*NodeBinaryOperator)
+			div.t = types.NewInt()
+			return div, nil
+		default:
+			return nil, invalid()
+		}
+	case Mul, Div, Mod, BitAnd, BitOr, BitXor, Shl, Shr:
+		if !lt.Kind().IsInteger() || !rt.Kind().IsInteger() {
+			return nil, invalid()
+		}
+		n.t = types.NewInt()
+	case Equal, NotEqual, SmallerThan, SmallerThanOrEqualTo, GreaterThan, GreaterThanOrEqualTo:
+		comparable := lt.Kind().IsInteger() && rt.Kind().IsInteger() ||
+			lt.Kind() == types.Pointer && types.Same(lt, rt) ||
+			lt.Kind() == types.Pointer && isNullPointerConstant(rhs) ||
+			rt.Kind() == types.Pointer && isNullPointerConstant(lhs)
+		if !comparable {
+			return nil, invalid()
+		}
+		n.t = types.NewInt()
+	default:
+		return nil, fail.Errorf("Unexpected binary operator %s", n.kind)
+	}
+	return n, nil
+}
+
+// scale multiplies the integer offset by the size of what ptr points to.
+func (c *checker) scale(n *NodeBinaryOperator, offset TypedNode, ptr types.Type) TypedNode {
+	return scaleAt(n.tok, offset, ptr)
+}
+
+func scaleAt(tok *token.Token, offset TypedNode, ptr types.Type) TypedNode {
+	size := newnodeImplNum(tok, ptr.PointingTo().Size())
+	mul := newBinaryOperator(tok, Mul, offset, size).(*NodeBinaryOperator)
+	mul.t = types.NewInt()
+	return mul
+}
+
+// ternary gives cond ? a : b the type both a and b can be converted to.
+func (c *checker) ternary(n *NodeTernary) (TypedNode, error) {
+	condition, err := c.scalar(n.condition, n.tok)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	then, err := c.expr(n.then)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	els, err := c.expr(n.els)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	n.condition, n.then, n.els = condition, then, els
+
+	tt, et := types.Decay(then.Type()), types.Decay(els.Type())
+	switch {
+	case tt.Kind().IsInteger() && et.Kind().IsInteger():
+		n.t = types.NewInt()
+	case types.Same(tt, et):
+		n.t = tt
+	case tt.Kind() == types.Pointer && isNullPointerConstant(els):
+		n.t = tt
+	case et.Kind() == types.Pointer && isNullPointerConstant(then):
+		n.t = et
+	default:
+		return nil, n.tok.Errorf("incompatible operand types (%q and %q)", tt, et)
+	}
+	return n, nil
+}
+
+func (c *checker) compoundAssign(n *NodeCompoundAssign) (TypedNode, error) {
+	lhs, err := c.lvalue(n.lhs.(Generatable))
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	if lhs == nil || lhs.Type().Kind() == types.Array {
+		return nil, n.tok.Errorf("expression is not assignable")
+	}
+	rhs, err := c.expr(n.rhs)
+	if err != nil {
+		return nil, fail.Wrap(err)
+	}
+	n.lhs, n.rhs = lhs, rhs
+
+	lt, rt := lhs.Type(), types.Decay(rhs.Type())
+	switch {
+	case lt.Kind().IsInteger() && rt.Kind().IsInteger():
+	case lt.Kind() == types.Pointer && rt.Kind().IsInteger() && (n.op == Add || n.op == Sub):
+		// p += n steps by elements as p + n does
+		n.rhs = scaleAt(n.tok, rhs, lt)
+	default:
+		return nil, n.tok.Errorf("invalid operands to %s (%q and %q)", n.tok.Str, lt, rt)
+	}
+	return n, nil
+}
+
+func (c *checker) funcCall(n *NodeFuncCall) (TypedNode, error) {
+	args := make([]TypedNode, len(n.args))
+	for i, a := range n.args {
+		arg, err := c.expr(a)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		if arg.Type().Kind().HasMembers() {
+			return nil, n.tok.Errorf("passing %q by value is not supported", arg.Type())
+		}
+		args[i] = arg
+		n.args[i] = arg
+	}
+
+	sig := n.sig
+	if f, ok := c.funcs[n.name]; ok && sig == nil {
+		// called before it is defined
+		sig = f.signature()
+	}
+	if sig == nil {
+		// an implicitly declared external function, which returns int
+		return n, nil
+	}
+	if sig.unprototyped {
+		n.t = sig.ret
+		return n, nil
+	}
+	if len(args) < len(sig.params) && sig.variadic {
+		return nil, n.tok.Errorf("function %q takes at least %d arguments, but %d given", n.name, len(sig.params), len(args))
+	}
+	if len(args) != len(sig.params) && !sig.variadic {
+		return nil, n.tok.Errorf("function %q takes %d arguments, but %d given", n.name, len(sig.params), len(args))
+	}
+	for i, param := range sig.params {
+		if !assignable(param, args[i]) {
+			return nil, n.tok.Errorf("passing %q to parameter of type %q in argument %d of %q", types.Decay(args[i].Type()), param, i+1, n.name)
+		}
+	}
+	n.t = sig.ret
+	return n, nil
+}
+
+// assignable reports whether the value of n can be stored to an object of type t.
+func assignable(t types.Type, n TypedNode) bool {
+	from := types.Decay(n.Type())
+	switch {
+	case t.Kind().IsInteger() && from.Kind().IsInteger():
+		return true
+	case t.Kind() == types.Pointer && isNullPointerConstant(n):
+		return true
+	}
+	return types.Same(t, from)
+}
+
+func isNullPointerConstant(n Generatable) bool {
+	num, ok := n.(*NodeNum)
+	return ok && num.val == 0
+}
@@ -0,0 +1,66 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeSizeof is either sizeof(type) or sizeof expr.
+// The operand is never evaluated, the size is taken from its static type when checked.
+type NodeSizeof struct {
+	tok     *token.Token
+	operand Generatable
+	of      types.Type
+}
+
+func newSizeofType(tok *token.Token, t types.Type) TypedNode {
+	return &NodeSizeof{tok: tok, of: t}
+}
+
+func newSizeofExpr(tok *token.Token, operand Generatable) TypedNode {
+	return &NodeSizeof{tok: tok, operand: operand}
+}
+
+func (n *NodeSizeof) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeSizeof) Generate() (string, error) {
+	if n.of == nil {
+		return "", fail.New("Unexpectedly unchecked sizeof")
+	}
+	return fmt.Sprintf("# Sizeof\n  push %d", n.of.Size()), nil
+}
+
+func (n *NodeSizeof) Type() types.Type {
+	return types.NewInt()
+}
+
+func (n *NodeSizeof) Kind() Kind {
+	return Sizeof
+}
+
+// Operand returns nil for sizeof(type).
+func (n *NodeSizeof) Operand() Generatable {
+	return n.operand
+}
+
+// Of returns the type whose size is taken, which is nil until checked for sizeof expr.
+func (n *NodeSizeof) Of() types.Type {
+	return n.of
+}
+
+func (n *NodeSizeof) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeSizeof) dump() *tree {
+	t := newTree(Sizeof).withType(n.Type())
+	if n.of != nil {
+		t.with("of", n.of.String()).with("size", n.of.Size())
+	}
+	return t.withNode("operand", n.operand)
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeStr is a string literal, an array of char placed in read-only data.
+type NodeStr struct {
+	tok   *token.Token
+	value []byte
+	label string
+}
+
+func newStr(tok *token.Token, value []byte) TypedNode {
+	return &NodeStr{
+		tok:   tok,
+		value: value,
+		label: fmt.Sprintf(".LC%d", newLabelNum()),
+	}
+}
+
+// GeneratePointer emits the literal right where it is used, switching to the read-only section and back.
+func (n *NodeStr) GeneratePointer() (string, error) {
+	bytes := make([]string, len(n.value)+1)
+	for i, b := range n.value {
+		bytes[i] = fmt.Sprintf("%d", b)
+	}
+	bytes[len(n.value)] = "0"
+
+	lines := []string{
+		fmt.Sprintf("## string literal %s", n.tok.Str),
+		codegenTarget.ReadOnlySection(),
+		n.label + ":",
+		"  .byte " + strings.Join(bytes, ", "),
+		".text",
+		fmt.Sprintf("  lea rax, [rip + %s]", n.label),
+		"  push rax",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeStr) Generate() (string, error) {
+	l, err := n.GeneratePointer()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return deref(l, n.Type())
+}
+
+// Type counts the terminating NUL, so that sizeof "abc" is 4.
+func (n *NodeStr) Type() types.Type {
+	return types.ArrayOf(types.NewChar(), len(n.value)+1)
+}
+
+func (n *NodeStr) Kind() Kind {
+	return Str
+}
+
+// Value returns the decoded contents without the terminating NUL.
+func (n *NodeStr) Value() []byte {
+	return n.value
+}
+
+func (n *NodeStr) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeStr) dump() *tree {
+	return newTree(Str).with("value", string(n.value)).withType(n.Type())
+}
@@ -0,0 +1,204 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/srvc/fail"
+)
+
+// a switch dispatches through a jump table when it has at least this many cases
+// and they fill at least half of the range between the smallest and the largest
+const jumpTableMinCases = 4
+
+type NodeSwitch struct {
+	tok       *token.Token
+	condition Generatable
+	stmt      Generatable
+	labels    *jumpTarget
+	// the case and default labels in the body, nested switches excluded
+	cases       []*NodeCase
+	defaultCase *NodeCase
+}
+
+func newSwitch(tok *token.Token, labels *jumpTarget) *NodeSwitch {
+	return &NodeSwitch{tok: tok, labels: labels}
+}
+
+func (n *NodeSwitch) Generate() (string, error) {
+	condition, err := n.condition.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	stmt, err := n.stmt.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+
+	// the value is compared as an int, whatever the upper half of rax holds
+	lines := []string{
+		"# switchstmt",
+		"## condition start",
+		condition,
+		"## condition end",
+		"  pop rax",
+	}
+	if n.dense() {
+		lines = append(lines, n.jumpTable()...)
+	} else {
+		lines = append(lines, n.compareChain()...)
+	}
+	lines = append(lines,
+		stmt,
+		n.labels.breakLabel+":",
+		"# switchstmt end",
+	)
+	return strings.Join(lines, "\n"), nil
+}
+
+// fallback is where control goes when no case matches.
+func (n *NodeSwitch) fallback() string {
+	if n.defaultCase != nil {
+		return n.defaultCase.label
+	}
+	return n.labels.breakLabel
+}
+
+func (n *NodeSwitch) bounds() (min, max int) {
+	min, max = n.cases[0].value, n.cases[0].value
+	for _, c := range n.cases[1:] {
+		if c.value < min {
+			min = c.value
+		}
+		if c.value > max {
+			max = c.value
+		}
+	}
+	return min, max
+}
+
+func (n *NodeSwitch) dense() bool {
+	if len(n.cases) < jumpTableMinCases {
+		return false
+	}
+	min, max := n.bounds()
+	return max-min+1 <= 2*len(n.cases)
+}
+
+func (n *NodeSwitch) compareChain() []string {
+	var lines []string
+	for _, c := range n.cases {
+		lines = append(lines,
+			fmt.Sprintf("  cmp eax, %d", c.value),
+			"  je "+c.label,
+		)
+	}
+	return append(lines, "  jmp "+n.fallback())
+}
+
+// jumpTable indexes a table in the read-only section by the value less the smallest case.
+// The entries are offsets from the table itself, which need no relocation when loaded.
+func (n *NodeSwitch) jumpTable() []string {
+	min, max := n.bounds()
+	table := fmt.Sprintf(".Ltable%d", newLabelNum())
+
+	entries := make([]string, max-min+1)
+	for i := range entries {
+		entries[i] = n.fallback()
+	}
+	for _, c := range n.cases {
+		entries[c.value-min] = c.label
+	}
+
+	lines := []string{
+		fmt.Sprintf("  sub eax, %d", min),
+		// unsigned, so that values below the smallest case wrap around and fail too
+		fmt.Sprintf("  cmp eax, %d", max-min),
+		"  ja " + n.fallback(),
+		fmt.Sprintf("  lea rdi, [rip + %s]", table),
+		"  movsxd rax, dword ptr [rdi + rax*4]",
+		"  add rax, rdi",
+		"  jmp rax",
+		codegenTarget.ReadOnlySection(),
+		"  .balign 4",
+		table + ":",
+	}
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("  .long %s - %s", e, table))
+	}
+	return append(lines, ".text")
+}
+
+func (n *NodeSwitch) Kind() Kind {
+	return Switch
+}
+
+func (n *NodeSwitch) Cond() Generatable {
+	return n.condition
+}
+
+func (n *NodeSwitch) Body() Generatable {
+	return n.stmt
+}
+
+func (n *NodeSwitch) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeSwitch) dump() *tree {
+	return newTree(Switch).withNode("cond", n.condition).withNode("body", n.stmt)
+}
+
+// NodeCase is a case or default label together with the statement it labels.
+type NodeCase struct {
+	tok       *token.Token
+	isDefault bool
+	value     int
+	label     string
+	stmt      Generatable
+}
+
+func newCase(tok *token.Token, isDefault bool, value int) *NodeCase {
+	return &NodeCase{
+		tok:       tok,
+		isDefault: isDefault,
+		value:     value,
+		label:     fmt.Sprintf(".Lcase%d", newLabelNum()),
+	}
+}
+
+func (n *NodeCase) Generate() (string, error) {
+	stmt, err := n.stmt.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	return n.label + ":\n" + stmt, nil
+}
+
+func (n *NodeCase) Kind() Kind {
+	if n.isDefault {
+		return Default
+	}
+	return Case
+}
+
+// Value is meaningless for a default label.
+func (n *NodeCase) Value() int {
+	return n.value
+}
+
+func (n *NodeCase) Body() Generatable {
+	return n.stmt
+}
+
+func (n *NodeCase) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeCase) dump() *tree {
+	if n.isDefault {
+		return newTree(Default).withNode("body", n.stmt)
+	}
+	return newTree(Case).with("value", n.value).withNode("body", n.stmt)
+}
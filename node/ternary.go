@@ -0,0 +1,153 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeTernary is cond ? a : b, which evaluates only one of a and b.
+type NodeTernary struct {
+	tok       *token.Token
+	condition Generatable
+	then      Generatable
+	els       Generatable
+	t         types.Type
+}
+
+func newTernary(tok *token.Token, c, then, els Generatable) TypedNode {
+	return &NodeTernary{
+		tok:       tok,
+		condition: c,
+		then:      then,
+		els:       els,
+	}
+}
+
+func (n *NodeTernary) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeTernary) Generate() (string, error) {
+	condition, err := n.condition.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	then, err := n.then.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	els, err := n.els.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	lend := fmt.Sprintf(".Lend%d", newLabelNum())
+	lelse := fmt.Sprintf(".Lelse%d", newLabelNum())
+	lines := []string{
+		"# ternary",
+		condition,
+		"  pop rax",
+		"  cmp rax, 0",
+		"  je  " + lelse,
+		then,
+		"  jmp " + lend,
+		lelse + ":",
+		els,
+		lend + ":",
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Type is nil until the node has been checked.
+func (n *NodeTernary) Type() types.Type {
+	return n.t
+}
+
+func (n *NodeTernary) Kind() Kind {
+	return Ternary
+}
+
+func (n *NodeTernary) Cond() Generatable {
+	return n.condition
+}
+
+func (n *NodeTernary) Then() Generatable {
+	return n.then
+}
+
+func (n *NodeTernary) Else() Generatable {
+	return n.els
+}
+
+func (n *NodeTernary) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeTernary) dump() *tree {
+	return newTree(Ternary).withType(n.Type()).withNode("cond", n.condition).withNode("then", n.then).withNode("else", n.els)
+}
+
+// NodeComma is a, b, which evaluates a only for its side effects and gives b.
+type NodeComma struct {
+	tok *token.Token
+	lhs Generatable
+	rhs Generatable
+}
+
+func newComma(tok *token.Token, lhs, rhs Generatable) TypedNode {
+	return &NodeComma{tok: tok, lhs: lhs, rhs: rhs}
+}
+
+func (n *NodeComma) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeComma) Generate() (string, error) {
+	l, err := n.lhs.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	r, err := n.rhs.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	lines := []string{
+		"# comma",
+		l,
+		"  pop rax",
+		r,
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Type is nil until the node has been checked.
+func (n *NodeComma) Type() types.Type {
+	t := n.rhs.(Typed).Type()
+	if t == nil {
+		return nil
+	}
+	return types.Decay(t)
+}
+
+func (n *NodeComma) Kind() Kind {
+	return Comma
+}
+
+func (n *NodeComma) Lhs() Generatable {
+	return n.lhs
+}
+
+func (n *NodeComma) Rhs() Generatable {
+	return n.rhs
+}
+
+func (n *NodeComma) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeComma) dump() *tree {
+	return newTree(Comma).withType(n.Type()).withNode("lhs", n.lhs).withNode("rhs", n.rhs)
+}
@@ -0,0 +1,70 @@
+package node
+
+import (
+	"strings"
+
+	"github.com/potsbo/gocc/token"
+	"github.com/potsbo/gocc/types"
+	"github.com/srvc/fail"
+)
+
+// NodeUnaryOperator is !x, which is 1 if x is 0 and 0 otherwise, or ~x, which flips every bit of x.
+type NodeUnaryOperator struct {
+	tok     *token.Token
+	kind    Kind
+	operand Generatable
+}
+
+func newUnaryOperator(tok *token.Token, kind Kind, operand Generatable) TypedNode {
+	return &NodeUnaryOperator{tok: tok, kind: kind, operand: operand}
+}
+
+func (n *NodeUnaryOperator) GeneratePointer() (string, error) {
+	return "", NoOffsetError
+}
+
+func (n *NodeUnaryOperator) Generate() (string, error) {
+	l, err := n.operand.Generate()
+	if err != nil {
+		return "", fail.Wrap(err)
+	}
+	lines := []string{
+		"# " + n.kind.String(),
+		l,
+		"  pop rax",
+	}
+	switch n.kind {
+	case Not:
+		lines = append(lines,
+			"  cmp rax, 0",
+			"  sete al",
+			"  movzx rax, al",
+		)
+	case BitNot:
+		lines = append(lines, "  not rax")
+	default:
+		return "", fail.Errorf("Unexpected unary operator %s", n.kind)
+	}
+	lines = append(lines, "  push rax")
+	return strings.Join(lines, "\n"), nil
+}
+
+func (n *NodeUnaryOperator) Type() types.Type {
+	return types.NewInt()
+}
+
+func (n *NodeUnaryOperator) Kind() Kind {
+	return n.kind
+}
+
+func (n *NodeUnaryOperator) Operand() Generatable {
+	return n.operand
+}
+
+func (n *NodeUnaryOperator) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeUnaryOperator) dump() *tree {
+	return newTree(n.kind).withType(n.Type()).withNode("operand", n.operand)
+}
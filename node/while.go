@@ -1,25 +1,29 @@
 package node
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/potsbo/gocc/token"
 	"github.com/srvc/fail"
 )
 
-type nodeWhile struct {
+type NodeWhile struct {
+	tok       *token.Token
 	condition Generatable
 	stmt      Generatable
+	labels    *jumpTarget
 }
 
-func newWhile(c, stmt Generatable) Generatable {
-	return &nodeWhile{
+func newWhile(tok *token.Token, c, stmt Generatable, labels *jumpTarget) Generatable {
+	return &NodeWhile{
+		tok:       tok,
 		condition: c,
 		stmt:      stmt,
+		labels:    labels,
 	}
 }
 
-func (n *nodeWhile) Generate() (string, error) {
+func (n *NodeWhile) Generate() (string, error) {
 	condition, err := n.condition.Generate()
 	if err != nil {
 		return "", fail.Wrap(err)
@@ -28,8 +32,8 @@ func (n *nodeWhile) Generate() (string, error) {
 	if err != nil {
 		return "", fail.Wrap(err)
 	}
-	lbegin := fmt.Sprintf(".Lbegin%d", newLabelNum())
-	lend := fmt.Sprintf(".Lend%d", newLabelNum())
+	lbegin := n.labels.continueLabel
+	lend := n.labels.breakLabel
 	lines := []string{
 		"# whilestmt",
 		lbegin + ":",
@@ -46,3 +50,23 @@ func (n *nodeWhile) Generate() (string, error) {
 	}
 	return strings.Join(lines, "\n"), nil
 }
+
+func (n *NodeWhile) Kind() Kind {
+	return While
+}
+
+func (n *NodeWhile) Cond() Generatable {
+	return n.condition
+}
+
+func (n *NodeWhile) Body() Generatable {
+	return n.stmt
+}
+
+func (n *NodeWhile) Pos() token.Position {
+	return n.tok.Pos
+}
+
+func (n *NodeWhile) dump() *tree {
+	return newTree(While).withNode("cond", n.condition).withNode("body", n.stmt)
+}
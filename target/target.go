@@ -0,0 +1,153 @@
+package target
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/srvc/fail"
+)
+
+type Format int
+
+const (
+	_ Format = iota
+	ELF
+	MachO
+)
+
+// Target describes the platform conventions which the generated assembly has to follow.
+type Target struct {
+	Triple string
+	Format Format
+}
+
+var (
+	Linux  = Target{Triple: "x86_64-linux-gnu", Format: ELF}
+	Darwin = Target{Triple: "x86_64-apple-darwin", Format: MachO}
+)
+
+// Host returns the target gocc itself is running on.
+func Host() Target {
+	if runtime.GOOS == "darwin" {
+		return Darwin
+	}
+	return Linux
+}
+
+// Lookup resolves a target triple such as x86_64-linux-gnu or x86_64-apple-darwin19.0.0.
+func Lookup(triple string) (Target, error) {
+	parts := strings.Split(triple, "-")
+	if parts[0] != "x86_64" && parts[0] != "amd64" {
+		return Target{}, fail.Errorf("unsupported target %q: only x86_64 is supported", triple)
+	}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "linux":
+			return Target{Triple: triple, Format: ELF}, nil
+		case strings.HasPrefix(p, "darwin"), strings.HasPrefix(p, "macos"):
+			return Target{Triple: triple, Format: MachO}, nil
+		}
+	}
+	return Target{}, fail.Errorf("unsupported target %q: expected a linux or darwin triple", triple)
+}
+
+// Symbol returns the assembly level name of the C identifier name.
+// Mach-O prefixes every C symbol with an underscore.
+func (t Target) Symbol(name string) string {
+	if t.Format == MachO {
+		return "_" + name
+	}
+	return name
+}
+
+// Header returns the directives which start every assembly file.
+func (t Target) Header() []string {
+	return []string{
+		".intel_syntax noprefix",
+		".text",
+	}
+}
+
+// Footer returns the directives which end every assembly file.
+func (t Target) Footer() []string {
+	if t.Format == ELF {
+		// without this note GNU ld assumes the object needs an executable stack
+		return []string{`.section .note.GNU-stack,"",@progbits`}
+	}
+	return nil
+}
+
+// Global returns the directive which exports the C symbol name.
+func (t Target) Global(name string) string {
+	return fmt.Sprintf(".globl %s", t.Symbol(name))
+}
+
+// FuncBegin returns the lines which start the definition of the function name.
+func (t Target) FuncBegin(name string) []string {
+	sym := t.Symbol(name)
+	lines := []string{".text"}
+	if t.Format == ELF {
+		lines = append(lines, fmt.Sprintf(".type %s, @function", sym))
+	}
+	return append(lines, sym+":")
+}
+
+// FuncEnd returns the lines which end the definition of the function name.
+func (t Target) FuncEnd(name string) []string {
+	if t.Format == ELF {
+		sym := t.Symbol(name)
+		return []string{fmt.Sprintf(".size %s, .-%s", sym, sym)}
+	}
+	return nil
+}
+
+// CallOperand returns the operand of a call instruction to the C function name.
+// On ELF calls go through the PLT so that functions in shared libraries can be reached from PIE.
+func (t Target) CallOperand(name string) string {
+	if t.Format == ELF {
+		return t.Symbol(name) + "@PLT"
+	}
+	return t.Symbol(name)
+}
+
+// Object returns the lines which start the definition of the data object name in the current section.
+func (t Target) Object(name string, size, align int) []string {
+	sym := t.Symbol(name)
+	lines := []string{}
+	if t.Format == ELF {
+		lines = append(lines,
+			fmt.Sprintf(".type %s, @object", sym),
+			fmt.Sprintf(".size %s, %d", sym, size),
+		)
+	}
+	return append(lines,
+		fmt.Sprintf(".p2align %d", log2(align)),
+		sym+":",
+	)
+}
+
+// ZeroFilled returns the lines which define the zero-initialized object name in the bss section.
+func (t Target) ZeroFilled(name string, size, align int) []string {
+	if t.Format == MachO {
+		return []string{fmt.Sprintf(".zerofill __DATA,__bss,%s,%d,%d", t.Symbol(name), size, log2(align))}
+	}
+	lines := append([]string{".bss"}, t.Object(name, size, align)...)
+	return append(lines, fmt.Sprintf("  .zero %d", size))
+}
+
+func log2(n int) int {
+	l := 0
+	for 1<<uint(l) < n {
+		l++
+	}
+	return l
+}
+
+// ReadOnlySection returns the directive which switches to the section for constant data.
+func (t Target) ReadOnlySection() string {
+	if t.Format == MachO {
+		return ".section __TEXT,__const"
+	}
+	return ".section .rodata"
+}
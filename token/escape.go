@@ -0,0 +1,113 @@
+package token
+
+import (
+	"strings"
+
+	"github.com/srvc/fail"
+)
+
+var simpleEscapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'?':  '?',
+}
+
+// readChar decodes the character at the head of s, which may be an escape sequence,
+// and returns its value and the number of bytes it spans.
+func readChar(s string) (byte, int, error) {
+	if s[0] != '\\' {
+		return s[0], 1, nil
+	}
+	if len(s) < 2 {
+		return 0, 0, fail.New("incomplete escape sequence")
+	}
+
+	if c, ok := simpleEscapes[s[1]]; ok {
+		return c, 2, nil
+	}
+	switch {
+	case s[1] == 'x':
+		v, n := 0, 2
+		for n < len(s) && isHexDigit(s[n]) {
+			v = v*16 + hexValue(s[n])
+			n++
+		}
+		if n == 2 {
+			return 0, 0, fail.New(`\x used with no following hex digits`)
+		}
+		if v > 0xff {
+			return 0, 0, fail.New("hex escape sequence out of range")
+		}
+		return byte(v), n, nil
+	case '0' <= s[1] && s[1] <= '7':
+		v, n := 0, 1
+		for n < len(s) && n < 4 && '0' <= s[n] && s[n] <= '7' {
+			v = v*8 + int(s[n]-'0')
+			n++
+		}
+		if v > 0xff {
+			return 0, 0, fail.New("octal escape sequence out of range")
+		}
+		return byte(v), n, nil
+	}
+	return 0, 0, fail.Errorf("unknown escape sequence '%s'", s[:2])
+}
+
+// quoted returns the length of the literal quoted by q at the head of s including the quotes,
+// and the position of a malformed character in it on error.
+func quoted(s string, q byte) (int, int, error) {
+	n := 1
+	for {
+		if n >= len(s) || s[n] == '\n' {
+			return 0, 0, fail.Errorf("missing terminating %c character", q)
+		}
+		if s[n] == q {
+			return n + 1, 0, nil
+		}
+		_, l, err := readChar(s[n:])
+		if err != nil {
+			return 0, n, err
+		}
+		n += l
+	}
+}
+
+// Unquote decodes the spelling of a string or character literal, such as "a\tb" or '\n'.
+func Unquote(lit string) ([]byte, error) {
+	if len(lit) < 2 {
+		return nil, fail.Errorf("malformed literal %s", lit)
+	}
+	s := lit[1 : len(lit)-1]
+	var b strings.Builder
+	for len(s) > 0 {
+		c, n, err := readChar(s)
+		if err != nil {
+			return nil, fail.Wrap(err)
+		}
+		b.WriteByte(c)
+		s = s[n:]
+	}
+	return []byte(b.String()), nil
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func hexValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	}
+	return int(c-'A') + 10
+}
@@ -0,0 +1,84 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Position is a location in a source file. Line and Column are 1-origin,
+// Offset is the 0-origin byte offset.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error is a diagnostic which points at a location in the source.
+type Error struct {
+	Pos  Position
+	Msg  string
+	Line string
+}
+
+// Error formats the diagnostic like gcc/clang do,
+// quoting the source line with a caret under the offending column.
+func (e *Error) Error() string {
+	// the column may lie past the quoted line, whose trailing "\r" has been stripped
+	prefix := e.Line
+	if e.Pos.Column-1 < len(prefix) {
+		prefix = prefix[:e.Pos.Column-1]
+	}
+	// keep tabs so that the caret lines up with the quoted line
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, prefix)
+
+	return fmt.Sprintf("%s: error: %s\n%s\n%s^", e.Pos, e.Msg, e.Line, indent)
+}
+
+type source struct {
+	file       string
+	text       string
+	lineStarts []int
+}
+
+func newSource(file, text string) *source {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &source{file: file, text: text, lineStarts: starts}
+}
+
+func (s *source) position(offset int) Position {
+	// index of the last line which starts at or before offset
+	i := sort.Search(len(s.lineStarts), func(i int) bool { return s.lineStarts[i] > offset }) - 1
+	return Position{File: s.file, Line: i + 1, Column: offset - s.lineStarts[i] + 1, Offset: offset}
+}
+
+func (s *source) line(pos Position) string {
+	begin := pos.Offset - (pos.Column - 1)
+	end := strings.IndexByte(s.text[begin:], '\n')
+	if end < 0 {
+		end = len(s.text) - begin
+	}
+	return strings.TrimSuffix(s.text[begin:begin+end], "\r")
+}
+
+func (s *source) errorAt(pos Position, msg string) *Error {
+	return &Error{Pos: pos, Msg: msg, Line: s.line(pos)}
+}
@@ -13,7 +13,7 @@ import (
 type Kind int
 
 var (
-	firstIdent = regexp.MustCompile(`([a-z]*)`).FindString
+	firstIdent = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`).FindString
 	alnum      = regexp.MustCompile(`^([a-z]|[A-Z]|[0-9]|_)*`).FindString
 )
 
@@ -24,9 +24,19 @@ const (
 	For
 	While
 	Else
+	Static
+	Sizeof
+	Typedef
+	Break
+	Continue
+	Switch
+	Case
+	Default
 	Reserved
 	Ident
 	Num
+	Char
+	String
 	Eof
 )
 
@@ -42,12 +52,32 @@ func (k Kind) String() string {
 		return "Else"
 	case Return:
 		return "Return"
+	case Static:
+		return "Static"
+	case Sizeof:
+		return "Sizeof"
+	case Typedef:
+		return "Typedef"
+	case Break:
+		return "Break"
+	case Continue:
+		return "Continue"
+	case Switch:
+		return "Switch"
+	case Case:
+		return "Case"
+	case Default:
+		return "Default"
 	case Reserved:
 		return "Reserved"
 	case Ident:
 		return "Ident"
 	case Num:
 		return "Num"
+	case Char:
+		return "Char"
+	case String:
+		return "String"
 	case Eof:
 		return "Eof"
 	default:
@@ -59,10 +89,12 @@ type Token struct {
 	Kind Kind
 	next *Token
 	Str  string
+	Pos  Position
+	src  *source
 }
 
 func (t Token) String() string {
-	return fmt.Sprintf("%q, type: %s", t.Str, t.Kind.String())
+	return fmt.Sprintf("%s: %q, type: %s", t.Pos, t.Str, t.Kind.String())
 }
 
 type Processor struct {
@@ -72,12 +104,51 @@ type Processor struct {
 func (t *Processor) Expect(op string) error {
 	cur := t.token
 	if cur.Kind != Reserved || cur.Str != op {
-		return fail.Errorf("Unexpected token %q, %q, expected %q, %q", cur.Kind.String(), cur.Str, Reserved.String(), op)
+		return t.ErrorAt(cur, "expected %q, but got %s", op, cur.describe())
 	}
 	t.token = cur.next
 	return nil
 }
 
+// Errorf returns a diagnostic pointing at the token which is about to be consumed.
+func (t *Processor) Errorf(format string, args ...interface{}) error {
+	return t.ErrorAt(t.token, format, args...)
+}
+
+// ErrorAt returns a diagnostic pointing at tok.
+func (t *Processor) ErrorAt(tok *Token, format string, args ...interface{}) error {
+	if tok == nil {
+		return fail.Errorf(format, args...)
+	}
+	return tok.Errorf(format, args...)
+}
+
+// Peek returns the token which is about to be consumed without consuming it.
+func (t *Processor) Peek() *Token {
+	return t.token
+}
+
+// Rewind moves back to tok, which must have been returned by Peek, to parse it again.
+func (t *Processor) Rewind(tok *Token) {
+	t.token = tok
+}
+
+// Errorf returns a diagnostic pointing at t.
+// Tokens which were not created by Tokenize carry no source, and get a plain error.
+func (t *Token) Errorf(format string, args ...interface{}) error {
+	if t.src == nil {
+		return fail.Errorf(format, args...)
+	}
+	return fail.Wrap(t.src.errorAt(t.Pos, fmt.Sprintf(format, args...)))
+}
+
+func (t *Token) describe() string {
+	if t.Kind == Eof {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.Str)
+}
+
 func (t *Processor) Inspect() []Token {
 	cur := t.token
 	tokens := []Token{}
@@ -155,11 +226,11 @@ func (t *Processor) ConsumeNum() (int, bool, error) {
 	if cur == nil {
 		return 0, false, nil
 	}
-	if cur.Kind != Num {
+	if cur.Kind != Num && cur.Kind != Char {
 		return 0, false, nil
 	}
 	t.token = cur.next
-	i, err := strconv.Atoi(cur.Str)
+	i, err := cur.value()
 	if err != nil {
 		return 0, false, fail.Wrap(err)
 	}
@@ -171,18 +242,31 @@ func (t *Processor) ExtractNum() (int, error) {
 	if cur == nil {
 		return 0, fail.Errorf("Current token is nil")
 	}
-	if cur.Kind != Num {
-		return 0, fail.Errorf("Unexpected Token %q, expected a Num", t.token.Str)
+	if cur.Kind != Num && cur.Kind != Char {
+		return 0, t.ErrorAt(cur, "expected a number, but got %s", cur.describe())
 	}
 	t.token = cur.next
 
-	i, err := strconv.Atoi(cur.Str)
+	i, err := cur.value()
 	if err != nil {
 		return 0, fail.Wrap(err)
 	}
 	return i, nil
 }
 
+// value returns the int value of a numeric literal.
+// A character literal such as 'a' has the value of its char, which is signed as on gcc.
+func (t *Token) value() (int, error) {
+	if t.Kind != Char {
+		return strconv.Atoi(t.Str)
+	}
+	b, err := Unquote(t.Str)
+	if err != nil {
+		return 0, fail.Wrap(err)
+	}
+	return int(int8(b[0])), nil
+}
+
 func (t *Processor) NextKind() Kind {
 	if t.token == nil {
 		return 0
@@ -197,61 +281,139 @@ func (t *Processor) NextStr() string {
 	return t.token.Str
 }
 
-func (t *Token) chain(k Kind, s string) *Token {
+func (t *Token) chain(k Kind, s string, pos Position) *Token {
 	n := Token{
 		Kind: k,
 		next: nil,
 		Str:  s,
+		Pos:  pos,
+		src:  t.src,
 	}
 
 	t.next = &n
 	return &n
 }
 
-func Tokenize(str string) (*Processor, error) {
-	var head Token
+// Tokenize splits src into tokens. file is only used to label positions.
+func Tokenize(file, src string) (*Processor, error) {
+	s := newSource(file, src)
+	head := Token{src: s}
 	cur := &head
+	str := src
 
 	for {
 		var i int
 		var err error
+		pos := s.position(len(src) - len(str))
 		if len(str) == 0 {
-			cur = cur.chain(Eof, "")
+			cur = cur.chain(Eof, "", pos)
 			break
 		}
 		if v := isReturn(str); v != "" {
-			cur = cur.chain(Return, v)
+			cur = cur.chain(Return, v, pos)
 			str = str[len(v):]
 			continue
 		}
 		if v := isIf(str); v != "" {
-			cur = cur.chain(If, v)
+			cur = cur.chain(If, v, pos)
 			str = str[len(v):]
 			continue
 		}
 		if v := isElse(str); v != "" {
-			cur = cur.chain(Else, v)
+			cur = cur.chain(Else, v, pos)
 			str = str[len(v):]
 			continue
 		}
 		if v := isWhile(str); v != "" {
-			cur = cur.chain(While, v)
+			cur = cur.chain(While, v, pos)
 			str = str[len(v):]
 			continue
 		}
 		if v := isFor(str); v != "" {
-			cur = cur.chain(For, v)
+			cur = cur.chain(For, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isStatic(str); v != "" {
+			cur = cur.chain(Static, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isSizeof(str); v != "" {
+			cur = cur.chain(Sizeof, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isTypedef(str); v != "" {
+			cur = cur.chain(Typedef, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isBreak(str); v != "" {
+			cur = cur.chain(Break, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isContinue(str); v != "" {
+			cur = cur.chain(Continue, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isSwitch(str); v != "" {
+			cur = cur.chain(Switch, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isCase(str); v != "" {
+			cur = cur.chain(Case, v, pos)
+			str = str[len(v):]
+			continue
+		}
+		if v := isDefault(str); v != "" {
+			cur = cur.chain(Default, v, pos)
 			str = str[len(v):]
 			continue
 		}
 
-		if str[0] == ' ' {
+		if isSpace(str[0]) {
 			str = str[1:]
 			continue
 		}
 
+		if str[0] == '"' {
+			n, bad, err := quoted(str, '"')
+			if err != nil {
+				return nil, fail.Wrap(s.errorAt(s.position(pos.Offset+bad), err.Error()))
+			}
+			cur = cur.chain(String, str[:n], pos)
+			str = str[n:]
+			continue
+		}
+
+		if str[0] == '\'' {
+			n, bad, err := quoted(str, '\'')
+			if err != nil {
+				return nil, fail.Wrap(s.errorAt(s.position(pos.Offset+bad), err.Error()))
+			}
+			if n == 2 {
+				return nil, fail.Wrap(s.errorAt(pos, "empty character constant"))
+			}
+			if _, l, _ := readChar(str[1:]); l+2 != n {
+				return nil, fail.Wrap(s.errorAt(pos, "multi-character character constant"))
+			}
+			cur = cur.chain(Char, str[:n], pos)
+			str = str[n:]
+			continue
+		}
+
+		if t := isTypeName(str); t != "" {
+			cur = cur.chain(Reserved, t, pos)
+			str = str[len(t):]
+			continue
+		}
+
 		if t := isReserved(str); t != "" {
-			cur = cur.chain(Reserved, t)
+			cur = cur.chain(Reserved, t, pos)
 			str = str[len(t):]
 			continue
 		}
@@ -261,56 +423,79 @@ func Tokenize(str string) (*Processor, error) {
 			if err != nil {
 				return nil, fail.Wrap(err)
 			}
-			cur = cur.chain(Num, strconv.Itoa(i))
+			cur = cur.chain(Num, strconv.Itoa(i), pos)
 			continue
 		}
 
 		if t := isIdent(str); t != "" {
-			cur = cur.chain(Ident, t)
+			cur = cur.chain(Ident, t, pos)
 			str = str[len(t):]
 			continue
 		}
 
-		return nil, fail.Errorf("No rule to parse %q", str)
+		return nil, fail.Wrap(s.errorAt(pos, fmt.Sprintf("no rule to parse %q", str[:1])))
 	}
 
-	return &Processor{head.next}, nil
+	return &Processor{token: head.next}, nil
 }
 
-func isReturn(str string) string {
-	target := "return"
-	nextStr := strings.TrimPrefix(str, target)
-	matched := alnum(nextStr)
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
 
-	if strings.HasPrefix(str, target) && matched == "" {
-		return target
-	}
-	return ""
+func isReturn(str string) string {
+	return isKeyword(str, "return")
 }
 func isIf(str string) string {
-	target := "if"
-	nextStr := strings.TrimPrefix(str, target)
-	matched := alnum(nextStr)
-
-	if strings.HasPrefix(str, target) && matched == "" {
-		return target
-	}
-	return ""
+	return isKeyword(str, "if")
 }
 
 func isElse(str string) string {
-	target := "else"
-	nextStr := strings.TrimPrefix(str, target)
-	matched := alnum(nextStr)
-
-	if strings.HasPrefix(str, target) && matched == "" {
-		return target
-	}
-	return ""
+	return isKeyword(str, "else")
 }
 
 func isWhile(str string) string {
-	target := "while"
+	return isKeyword(str, "while")
+}
+
+func isFor(str string) string {
+	return isKeyword(str, "for")
+}
+
+func isStatic(str string) string {
+	return isKeyword(str, "static")
+}
+
+func isSizeof(str string) string {
+	return isKeyword(str, "sizeof")
+}
+
+func isTypedef(str string) string {
+	return isKeyword(str, "typedef")
+}
+
+func isBreak(str string) string {
+	return isKeyword(str, "break")
+}
+
+func isContinue(str string) string {
+	return isKeyword(str, "continue")
+}
+
+func isSwitch(str string) string {
+	return isKeyword(str, "switch")
+}
+
+func isCase(str string) string {
+	return isKeyword(str, "case")
+}
+
+func isDefault(str string) string {
+	return isKeyword(str, "default")
+}
+
+// isKeyword returns target if str starts with target as a whole word.
+func isKeyword(str, target string) string {
 	nextStr := strings.TrimPrefix(str, target)
 	matched := alnum(nextStr)
 
@@ -320,13 +505,12 @@ func isWhile(str string) string {
 	return ""
 }
 
-func isFor(str string) string {
-	target := "for"
-	nextStr := strings.TrimPrefix(str, target)
-	matched := alnum(nextStr)
-
-	if strings.HasPrefix(str, target) && matched == "" {
-		return target
+// isTypeName matches the built-in type names, which the parser consumes as reserved words.
+func isTypeName(str string) string {
+	for _, t := range []string{"int", "char", "struct", "union", "enum"} {
+		if v := isKeyword(str, t); v != "" {
+			return v
+		}
 	}
 	return ""
 }
@@ -336,7 +520,12 @@ func isIdent(str string) string {
 }
 
 func isReserved(str string) string {
-	tokens := []string{"+", "-", "*", "/", "(", ")", "==", ">=", "<=", ">", "<", "!=", ";", "=", "{", "}", ",", "&", "int"}
+	// longer ones first, so that "<<=" is not taken for "<<" and "="
+	tokens := []string{
+		"<<=", ">>=", "...",
+		"->", "++", "--", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "==", "!=", "<=", ">=", "<<", ">>", "&&", "||",
+		"+", "-", "*", "/", "%", "(", ")", ">", "<", "!", ";", "=", "{", "}", "[", "]", ",", "&", "|", "^", "~", "?", ":", ".",
+	}
 	for _, t := range tokens {
 		if strings.HasPrefix(str, t) {
 			return t
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/srvc/fail"
+)
+
+// cc is the system compiler driver, which gocc delegates assembling and linking to.
+const cc = "cc"
+
+// build runs every input through as many stages as opts.mode asks for.
+func build(opts *options) error {
+	tmp, err := ioutil.TempDir("", "gocc")
+	if err != nil {
+		return fail.Wrap(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if opts.mode == modeDumpAST {
+		return fail.Wrap(dumpAll(opts))
+	}
+
+	linkInputs := []string{}
+	for i, in := range opts.inputs {
+		var asm string
+		switch kindOf(in) {
+		case linkerInput:
+			if opts.mode != modeLink {
+				warnf("%s: linker input file unused because linking not done", in)
+				continue
+			}
+			linkInputs = append(linkInputs, in)
+			continue
+		case asmSource:
+			if opts.mode == modeCompile {
+				warnf("%s: assembler input file unused because assembling not done", in)
+				continue
+			}
+			asm = in
+		case cSource:
+			asm = filepath.Join(tmp, fmt.Sprintf("%d.s", i))
+			if opts.mode == modeCompile {
+				if asm, err = opts.outputFor(in, ".s"); err != nil {
+					return fail.Wrap(err)
+				}
+			}
+			if err := compileFile(in, asm, opts.target); err != nil {
+				return fail.Wrap(err)
+			}
+		}
+		if opts.mode == modeCompile {
+			continue
+		}
+
+		obj := filepath.Join(tmp, fmt.Sprintf("%d.o", i))
+		if opts.mode == modeAssemble {
+			if obj, err = opts.outputFor(in, ".o"); err != nil {
+				return fail.Wrap(err)
+			}
+		}
+		if err := assemble(asm, obj); err != nil {
+			return fail.Wrap(err)
+		}
+		linkInputs = append(linkInputs, obj)
+	}
+
+	if opts.mode != modeLink {
+		return nil
+	}
+	out := opts.output
+	if out == "" {
+		out = "a.out"
+	}
+	return fail.Wrap(link(linkInputs, out))
+}
+
+// dumpAll writes the trees of every C input to a single output, stdout by default.
+func dumpAll(opts *options) error {
+	var buf bytes.Buffer
+	for _, in := range opts.inputs {
+		if kindOf(in) != cSource {
+			warnf("%s: input file unused because no C source is parsed", in)
+			continue
+		}
+		if err := dumpFile(in, opts.astForm, &buf); err != nil {
+			return fail.Wrap(err)
+		}
+	}
+	return fail.Wrap(writeOutput(opts.output, &buf))
+}
+
+func assemble(asm, obj string) error {
+	if err := runTool(cc, "-c", asm, "-o", obj); err != nil {
+		return fail.Errorf("failed to assemble %s: %v", asm, err)
+	}
+	return nil
+}
+
+func link(inputs []string, out string) error {
+	args := append([]string{"-o", out}, inputs...)
+	if err := runTool(cc, args...); err != nil {
+		return fail.Errorf("failed to link %s: %v", out, err)
+	}
+	return nil
+}
+
+func runTool(name string, args ...string) error {
+	if debug {
+		fmt.Fprintln(os.Stderr, name, args)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "gocc: warning: "+format+"\n", args...)
+}
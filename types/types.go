@@ -1,24 +1,47 @@
 package types
 
+import "fmt"
+
 type Kind int
 
 const (
 	_ Kind = iota
 	Int
 	Pointer
+	Char
+	Array
+	Struct
+	Union
 )
 
 type Type interface {
 	Kind() Kind
+	// PointingTo returns the pointee of a pointer or the element of an array
 	PointingTo() Type
+	// Len returns the number of elements of an array
+	Len() int
+	Size() int
+	Align() int
+	// Members returns the members of a struct in order, nil while it is incomplete
+	Members() []Member
+	String() string
+}
+
+// Member is a member of a struct, placed Offset bytes from the head of it.
+type Member struct {
+	Name   string
+	Type   Type
+	Offset int
 }
 
 func (k Kind) Size() int {
 	switch k {
 	case Int:
-		return 4 // TODO
-	case Pointer:
 		return 4
+	case Pointer:
+		return 8
+	case Char:
+		return 1
 	}
 	return 0
 }
@@ -26,6 +49,16 @@ func (k Kind) Size() int {
 type typeImpl struct {
 	kind       Kind
 	pointingTo Type
+	length     int
+	// shared by every copy of a struct type, so that it can be completed after it is referred to
+	layout *structLayout
+}
+
+type structLayout struct {
+	tag     string
+	members []Member
+	size    int
+	align   int
 }
 
 func (t typeImpl) Kind() Kind {
@@ -34,11 +67,69 @@ func (t typeImpl) Kind() Kind {
 func (t typeImpl) PointingTo() Type {
 	return t.pointingTo
 }
+func (t typeImpl) Len() int {
+	return t.length
+}
+
+func (t typeImpl) Size() int {
+	switch t.kind {
+	case Array:
+		return t.length * t.pointingTo.Size()
+	case Struct, Union:
+		return t.layout.size
+	}
+	return t.kind.Size()
+}
+
+func (t typeImpl) Align() int {
+	switch t.kind {
+	case Array:
+		return t.pointingTo.Align()
+	case Struct, Union:
+		return t.layout.align
+	}
+	return t.kind.Size()
+}
+
+func (t typeImpl) Members() []Member {
+	if !t.kind.HasMembers() {
+		return nil
+	}
+	return t.layout.members
+}
+
+// String spells the type as it would be written in C, e.g. "int*" or "char[2][3]".
+func (t typeImpl) String() string {
+	switch t.kind {
+	case Pointer:
+		return t.pointingTo.String() + "*"
+	case Array:
+		dims := ""
+		var elem Type = t
+		for elem.Kind() == Array {
+			dims += fmt.Sprintf("[%d]", elem.Len())
+			elem = elem.PointingTo()
+		}
+		return elem.String() + dims
+	case Struct, Union:
+		if t.layout.tag == "" {
+			return t.kind.Identifier() + " <anonymous>"
+		}
+		return t.kind.Identifier() + " " + t.layout.tag
+	}
+	return t.kind.Identifier()
+}
 
 func (k Kind) Identifier() string {
 	switch k {
 	case Int:
 		return "int"
+	case Char:
+		return "char"
+	case Struct:
+		return "struct"
+	case Union:
+		return "union"
 	}
 
 	panic("Unreachable code")
@@ -52,12 +143,107 @@ func PointingTo(t Type) Type {
 	return &typeImpl{kind: Pointer, pointingTo: t}
 }
 
+func ArrayOf(t Type, length int) Type {
+	return &typeImpl{kind: Array, pointingTo: t, length: length}
+}
+
+// Decay returns the pointer type an array of t turns into when its value is used.
+// Other types are returned as is.
+func Decay(t Type) Type {
+	if t.Kind() == Array {
+		return PointingTo(t.PointingTo())
+	}
+	return t
+}
+
+// StructOf returns a new struct type, which is incomplete until SetMembers is called.
+// An empty tag makes an anonymous struct.
+func StructOf(tag string) Type {
+	return typeImpl{kind: Struct, layout: &structLayout{tag: tag}}
+}
+
+// UnionOf returns a new union type, which is incomplete until SetMembers is called.
+func UnionOf(tag string) Type {
+	return typeImpl{kind: Union, layout: &structLayout{tag: tag}}
+}
+
+// SetMembers completes the struct or union t, laying out the members in order.
+// Each member of a struct is aligned to its own alignment, and the struct to the largest of them.
+// The members of a union all start at offset 0, and it is as large as the largest of them.
+func SetMembers(t Type, members []Member) {
+	l := t.(typeImpl).layout
+	size, align := 0, 1
+	for i, m := range members {
+		if t.Kind() == Struct {
+			members[i].Offset = alignTo(size, m.Type.Align())
+		}
+		if end := members[i].Offset + m.Type.Size(); end > size {
+			size = end
+		}
+		if m.Type.Align() > align {
+			align = m.Type.Align()
+		}
+	}
+	l.members, l.size, l.align = members, alignTo(size, align), align
+}
+
+// MemberOf looks up the member of the struct or union t by name.
+func MemberOf(t Type, name string) (Member, bool) {
+	for _, m := range t.Members() {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Member{}, false
+}
+
+// Incomplete reports whether t is a struct or union which has been referred to but not defined yet.
+func Incomplete(t Type) bool {
+	return t.Kind().HasMembers() && t.Members() == nil
+}
+
+func alignTo(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
 func All() []Kind {
 	return []Kind{
 		Int,
+		Char,
 	}
 }
 
 func NewInt() Type {
 	return typeImpl{kind: Int}
 }
+
+func NewChar() Type {
+	return typeImpl{kind: Char}
+}
+
+// IsInteger reports whether values of k take part in integer arithmetic.
+func (k Kind) IsInteger() bool {
+	return k == Int || k == Char
+}
+
+// HasMembers reports whether values of k are made of named members.
+func (k Kind) HasMembers() bool {
+	return k == Struct || k == Union
+}
+
+// Same reports whether a and b denote the same type.
+func Same(a, b Type) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case Pointer:
+		return Same(a.PointingTo(), b.PointingTo())
+	case Array:
+		return a.Len() == b.Len() && Same(a.PointingTo(), b.PointingTo())
+	case Struct, Union:
+		// every struct definition is a distinct type
+		return a.(typeImpl).layout == b.(typeImpl).layout
+	}
+	return true
+}